@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	"log"
 	"myvault-backend/configs"
 	"myvault-backend/internal/handlers"
 	"myvault-backend/internal/middleware"
 	"myvault-backend/internal/models"
 	"myvault-backend/internal/services"
+	"myvault-backend/pkg/ai"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -20,6 +22,9 @@ func main() {
 
 	// 初始化配置
 	cfg := configs.Load()
+	if err := cfg.Validate(); err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
 
 	// 连接数据库
 	db, err := configs.ConnectDB(cfg)
@@ -41,14 +46,67 @@ func main() {
 	// 初始化服务
 	userService := services.NewUserService(db)
 	authService := services.NewAuthService(db, cfg.JWTSecret)
-	githubService := services.NewGithubService(cfg.GithubClientID, cfg.GithubClientSecret)
-	aiService := services.NewAIService(cfg.OpenAIAPIKey)
-	activityService := services.NewActivityService(db, rdb, aiService)
+	githubService := services.NewGithubService(cfg.GithubClientID, cfg.GithubClientSecret, cfg.GithubDetailWorkers, rdb)
+	gitlabService := services.NewGitlabService(cfg.GitlabBaseURL)
+	giteaService := services.NewGiteaService(cfg.GiteaBaseURL)
+	wakatimeService := services.NewWakatimeService(cfg.WakatimeBaseURL)
+	aiProvider, err := ai.NewProvider(cfg.AIProvider, ai.Options{
+		BaseURL:    cfg.AIBaseURL,
+		APIKey:     cfg.OpenAIAPIKey,
+		Model:      cfg.AIModel,
+		Timeout:    cfg.AITimeout,
+		MaxRetries: cfg.AIMaxRetries,
+	})
+	if err != nil {
+		log.Fatal("Failed to create AI provider:", err)
+	}
+	log.Printf("Using AI provider: %s", aiProvider.Name())
+	// 模型服务持续故障时熔断，避免每天的摘要都等待超时
+	aiService := services.NewAIService(ai.NewCircuitBreaker(aiProvider, cfg.AIBreakerThreshold, cfg.AIBreakerCooldown))
+	repositoryService := services.NewRepositoryService(db)
+	calendarService := services.NewCalendarService(db)
+
+	// 注册数据源，同步时依次处理用户已绑定的数据源
+	sourceRegistry := services.NewSourceRegistry(
+		services.NewGithubSource(githubService, repositoryService),
+		services.NewGitlabSource(gitlabService, repositoryService),
+		services.NewGiteaSource(giteaService, repositoryService),
+		services.NewLocalGitSource(cfg.LocalGitRoots, repositoryService),
+		services.NewWakatimeSource(wakatimeService),
+		services.NewCalendarSource(calendarService),
+	)
+	estimator := services.NewTimeEstimator(cfg.CodingSessionGap, cfg.CodingSessionPadding)
+	activityService := services.NewActivityService(db, rdb, aiService, repositoryService, sourceRegistry, estimator)
+
+	webhookService := services.NewWebhookService(db, activityService)
+	rollupService := services.NewRollupService(db, rdb, aiService, repositoryService)
+	syncJobService := services.NewSyncJobService(rdb, activityService, cfg.SyncWorkers)
+	summaryJobService := services.NewSummaryJobService(db, rdb, activityService, cfg.SummaryWorkers, cfg.SummaryMaxAttempts)
+
+	// 启动同步任务worker
+	go syncJobService.Start(context.Background())
+
+	// 启动AI摘要worker
+	go summaryJobService.Start(context.Background())
+	go rollupService.Start(context.Background())
+
+	// 启动后台定时同步
+	if cfg.SyncEnabled {
+		scheduler := services.NewSyncScheduler(db, rdb, activityService, cfg.SyncInterval, cfg.SyncJitter)
+		go scheduler.Start(context.Background())
+	}
 
 	// 初始化处理器
 	authHandler := handlers.NewAuthHandler(authService, userService)
 	githubHandler := handlers.NewGithubHandler(githubService, userService)
-	activityHandler := handlers.NewActivityHandler(activityService)
+	gitlabHandler := handlers.NewGitlabHandler(gitlabService, userService)
+	giteaHandler := handlers.NewGiteaHandler(giteaService, userService)
+	wakatimeHandler := handlers.NewWakatimeHandler(wakatimeService, userService)
+	activityHandler := handlers.NewActivityHandler(activityService, syncJobService)
+	repositoryHandler := handlers.NewRepositoryHandler(repositoryService)
+	calendarHandler := handlers.NewCalendarHandler(calendarService, activityService)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	rollupHandler := handlers.NewRollupHandler(rollupService)
 
 	// 设置路由
 	router := gin.Default()
@@ -69,17 +127,50 @@ func main() {
 			auth.GET("/github/callback", githubHandler.GithubCallback)
 		}
 
+		// 外部工具推送事件，通过token和签名认证
+		api.POST("/hooks/:token", webhookHandler.ReceiveEvent)
+
 		// 受保护的路由
 		protected := api.Group("/")
 		protected.Use(middleware.AuthMiddleware(authService))
 		{
 			protected.GET("/user", authHandler.GetUser)
 			protected.PUT("/user", authHandler.UpdateUser)
+			protected.PUT("/user/gitlab", gitlabHandler.ConnectGitlab)
+			protected.DELETE("/user/gitlab", gitlabHandler.DisconnectGitlab)
+			protected.PUT("/user/gitea", giteaHandler.ConnectGitea)
+			protected.DELETE("/user/gitea", giteaHandler.DisconnectGitea)
+			protected.PUT("/user/wakatime", wakatimeHandler.ConnectWakatime)
+			protected.DELETE("/user/wakatime", wakatimeHandler.DisconnectWakatime)
+			protected.GET("/user/hooks", webhookHandler.GetWebhooks)
+			protected.POST("/user/hooks", webhookHandler.CreateWebhook)
+			protected.POST("/user/hooks/:id/rotate", webhookHandler.RotateWebhook)
+			protected.DELETE("/user/hooks/:id", webhookHandler.DeleteWebhook)
 			
 			// 活动相关
 			protected.GET("/activities", activityHandler.GetActivities)
 			protected.GET("/activities/:id", activityHandler.GetActivity)
+			protected.POST("/activities", activityHandler.CreateActivity)
+			protected.POST("/activities/:id/summary", activityHandler.RegenerateSummary)
+			protected.PUT("/activities/entries/:entryId", activityHandler.UpdateManualEntry)
+			protected.DELETE("/activities/entries/:entryId", activityHandler.DeleteManualEntry)
 			protected.POST("/activities/sync", activityHandler.SyncActivities)
+			protected.GET("/activities/sync/:jobId", activityHandler.GetSyncJob)
+
+			// 周、月、年汇总
+			protected.GET("/summaries/week/:isoWeek", rollupHandler.GetWeekSummary)
+			protected.GET("/summaries/month/:month", rollupHandler.GetMonthSummary)
+			protected.GET("/summaries/year/:year", rollupHandler.GetYearSummary)
+
+			// 仓库相关
+			protected.GET("/repositories", repositoryHandler.GetRepositories)
+			protected.PUT("/repositories/:id", repositoryHandler.UpdateRepository)
+
+			// 日历相关
+			protected.GET("/calendars", calendarHandler.GetCalendars)
+			protected.POST("/calendars", calendarHandler.SubscribeCalendar)
+			protected.POST("/calendars/upload", calendarHandler.UploadCalendar)
+			protected.DELETE("/calendars/:id", calendarHandler.DeleteCalendar)
 		}
 	}
 
@@ -3,6 +3,8 @@ package configs
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -23,8 +25,28 @@ type Config struct {
 	Port                 string
 	GithubClientID       string
 	GithubClientSecret   string
+	GithubDetailWorkers  int
+	GitlabBaseURL        string
+	GiteaBaseURL         string
+	WakatimeBaseURL      string
+	LocalGitRoots        []string
 	OpenAIAPIKey         string
+	AIProvider           string
+	AIBaseURL            string
+	AIModel              string
+	AITimeout            time.Duration
+	AIMaxRetries         int
+	AIBreakerThreshold   int
+	AIBreakerCooldown    time.Duration
 	Environment          string
+	SyncEnabled          bool
+	SyncInterval         time.Duration
+	SyncJitter           time.Duration
+	SyncWorkers          int
+	SummaryWorkers       int
+	SummaryMaxAttempts   int
+	CodingSessionGap     time.Duration
+	CodingSessionPadding time.Duration
 }
 
 func Load() *Config {
@@ -41,11 +63,42 @@ func Load() *Config {
 		Port:                 getEnv("PORT", "8081"),
 		GithubClientID:       getEnv("GITHUB_CLIENT_ID", ""),
 		GithubClientSecret:   getEnv("GITHUB_CLIENT_SECRET", ""),
+		GithubDetailWorkers:  getEnvInt("GITHUB_DETAIL_WORKERS", 4),
+		GitlabBaseURL:        getEnv("GITLAB_BASE_URL", "https://gitlab.com"),
+		GiteaBaseURL:         getEnv("GITEA_BASE_URL", ""),
+		WakatimeBaseURL:      getEnv("WAKATIME_BASE_URL", "https://wakatime.com/api/v1"),
+		LocalGitRoots:        getEnvList("LOCAL_GIT_ROOTS"),
 		OpenAIAPIKey:         getEnv("OPENAI_API_KEY", ""),
+		AIProvider:           getEnv("AI_PROVIDER", "openai"),
+		AIBaseURL:            getEnv("AI_BASE_URL", ""),
+		AIModel:              getEnv("AI_MODEL", ""),
+		AITimeout:            getEnvDuration("AI_TIMEOUT", 0),
+		AIMaxRetries:         getEnvInt("AI_MAX_RETRIES", 3),
+		AIBreakerThreshold:   getEnvInt("AI_BREAKER_THRESHOLD", 5),
+		AIBreakerCooldown:    getEnvDuration("AI_BREAKER_COOLDOWN", 5*time.Minute),
 		Environment:          getEnv("ENVIRONMENT", "development"),
+		SyncEnabled:          getEnvBool("SYNC_ENABLED", true),
+		SyncInterval:         getEnvDuration("SYNC_INTERVAL", time.Hour),
+		SyncJitter:           getEnvDuration("SYNC_JITTER", 5*time.Minute),
+		SyncWorkers:          getEnvInt("SYNC_WORKERS", 2),
+		SummaryWorkers:       getEnvInt("SUMMARY_WORKERS", 2),
+		SummaryMaxAttempts:   getEnvInt("SUMMARY_MAX_ATTEMPTS", 5),
+		CodingSessionGap:     getEnvDuration("CODING_SESSION_GAP", 2*time.Hour),
+		CodingSessionPadding: getEnvDuration("CODING_SESSION_PADDING", 2*time.Hour),
 	}
 }
 
+// Validate 检查无法回退到默认值的配置
+func (c *Config) Validate() error {
+	if c.SyncEnabled && c.SyncInterval <= 0 {
+		return fmt.Errorf("SYNC_INTERVAL must be positive, got %s", c.SyncInterval)
+	}
+	if c.SyncJitter < 0 {
+		return fmt.Errorf("SYNC_JITTER must not be negative, got %s", c.SyncJitter)
+	}
+	return nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -53,6 +106,38 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvBool(key string, defaultValue bool) bool {
+	if value, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// 逗号分隔的列表，忽略空项
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
 func ConnectDB(cfg *Config) (*gorm.DB, error) {
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
 		cfg.DBUser,
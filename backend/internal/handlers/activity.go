@@ -1,27 +1,40 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
 	"myvault-backend/internal/models"
+	"myvault-backend/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 type ActivityHandler struct {
 	activityService ActivityService
+	syncJobService  SyncJobService
 }
 
 type ActivityService interface {
 	GetUserActivities(userID uint, limit int, offset int) ([]models.Activity, error)
 	GetActivityByID(userID, activityID uint) (*models.Activity, error)
-	SyncActivities(userID uint, force bool) error
 	GetTodayActivity(userID uint) (*models.Activity, error)
+	CreateManualEntry(ctx context.Context, userID uint, req *models.ActivityRequest) (*models.Activity, error)
+	UpdateManualEntry(ctx context.Context, userID, entryID uint, req *models.UpdateManualEntryRequest) (*models.Activity, error)
+	DeleteManualEntry(ctx context.Context, userID, entryID uint) (*models.Activity, error)
+	RegenerateSummary(ctx context.Context, userID, activityID uint) (*models.Activity, error)
 }
 
-func NewActivityHandler(activityService ActivityService) *ActivityHandler {
+type SyncJobService interface {
+	EnqueueSync(userID uint, force bool) (*models.SyncJob, error)
+	GetSyncJob(userID uint, jobID string) (*models.SyncJob, error)
+}
+
+func NewActivityHandler(activityService ActivityService, syncJobService SyncJobService) *ActivityHandler {
 	return &ActivityHandler{
 		activityService: activityService,
+		syncJobService:  syncJobService,
 	}
 }
 
@@ -81,6 +94,126 @@ func (h *ActivityHandler) GetActivity(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"activity": activity})
 }
 
+// 在某一天添加手动记录
+func (h *ActivityHandler) CreateActivity(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.ActivityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	activity, err := h.activityService.CreateManualEntry(c.Request.Context(), userID.(uint), &req)
+	if errors.Is(err, services.ErrLockTimeout) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Activity is being updated, please retry"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create activity entry"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"activity": activity})
+}
+
+func (h *ActivityHandler) UpdateManualEntry(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	entryID, err := strconv.ParseUint(c.Param("entryId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid entry ID"})
+		return
+	}
+
+	var req models.UpdateManualEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	activity, err := h.activityService.UpdateManualEntry(c.Request.Context(), userID.(uint), uint(entryID), &req)
+	if errors.Is(err, services.ErrManualEntryNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Entry not found"})
+		return
+	}
+	if errors.Is(err, services.ErrLockTimeout) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Activity is being updated, please retry"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update activity entry"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"activity": activity})
+}
+
+func (h *ActivityHandler) DeleteManualEntry(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	entryID, err := strconv.ParseUint(c.Param("entryId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid entry ID"})
+		return
+	}
+
+	activity, err := h.activityService.DeleteManualEntry(c.Request.Context(), userID.(uint), uint(entryID))
+	if errors.Is(err, services.ErrManualEntryNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Entry not found"})
+		return
+	}
+	if errors.Is(err, services.ErrLockTimeout) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Activity is being updated, please retry"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete activity entry"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"activity": activity})
+}
+
+// 重新生成摘要，结果由后台worker写入，通过GetActivity查看summary_status
+func (h *ActivityHandler) RegenerateSummary(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	activityID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid activity ID"})
+		return
+	}
+
+	activity, err := h.activityService.RegenerateSummary(c.Request.Context(), userID.(uint), uint(activityID))
+	if errors.Is(err, services.ErrActivityNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Activity not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to regenerate summary"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"activity": activity})
+}
+
 func (h *ActivityHandler) SyncActivities(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -94,13 +227,33 @@ func (h *ActivityHandler) SyncActivities(c *gin.Context) {
 		return
 	}
 
-	err := h.activityService.SyncActivities(userID.(uint), req.Force)
+	job, err := h.syncJobService.EnqueueSync(userID.(uint), req.Force)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sync activities"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Activities synced successfully"})
+	c.JSON(http.StatusAccepted, gin.H{"job": job})
+}
+
+func (h *ActivityHandler) GetSyncJob(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	job, err := h.syncJobService.GetSyncJob(userID.(uint), c.Param("jobId"))
+	if errors.Is(err, services.ErrSyncJobNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sync job not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get sync job"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"job": job})
 }
 
 func (h *ActivityHandler) GetTodayActivity(c *gin.Context) {
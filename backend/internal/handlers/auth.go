@@ -23,7 +23,13 @@ type UserService interface {
 	GetUserByID(id uint) (*models.User, error)
 	UpdateUser(id uint, req *models.UpdateUserRequest) (*models.User, error)
 	VerifyPassword(user *models.User, password string) error
-	GetOrCreateGithubUser(githubID, username, email, avatar string) (*models.User, error)
+	GetOrCreateGithubUser(githubID, username, email, avatar, accessToken string) (*models.User, error)
+	ConnectGitlab(id uint, username, email, baseURL, accessToken string) (*models.User, error)
+	DisconnectGitlab(id uint) (*models.User, error)
+	ConnectGitea(id uint, username, email, baseURL, accessToken string) (*models.User, error)
+	DisconnectGitea(id uint) (*models.User, error)
+	ConnectWakatime(id uint, username, baseURL, apiKey string) (*models.User, error)
+	DisconnectWakatime(id uint) (*models.User, error)
 }
 
 func NewAuthHandler(authService AuthService, userService UserService) *AuthHandler {
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"myvault-backend/internal/models"
+	"myvault-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CalendarHandler struct {
+	calendarService CalendarService
+	meetingService  MeetingService
+}
+
+type CalendarService interface {
+	GetUserCalendars(userID uint) ([]models.Calendar, error)
+	SubscribeCalendar(userID uint, req *models.SubscribeCalendarRequest) (*models.Calendar, error)
+	UploadCalendar(userID uint, name string, r io.Reader) (*models.Calendar, error)
+	DeleteCalendar(userID, calendarID uint) error
+}
+
+// MeetingService 管理已导入时间轴的会议
+type MeetingService interface {
+	RemoveCalendarMeetings(ctx context.Context, userID, calendarID uint) error
+}
+
+func NewCalendarHandler(calendarService CalendarService, meetingService MeetingService) *CalendarHandler {
+	return &CalendarHandler{
+		calendarService: calendarService,
+		meetingService:  meetingService,
+	}
+}
+
+func (h *CalendarHandler) GetCalendars(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	calendars, err := h.calendarService.GetUserCalendars(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get calendars"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"calendars": calendars,
+		"total":     len(calendars),
+	})
+}
+
+// 订阅ICS地址
+func (h *CalendarHandler) SubscribeCalendar(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.SubscribeCalendarRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 不返回详细原因，避免泄露订阅地址返回的内容
+	calendar, err := h.calendarService.SubscribeCalendar(userID.(uint), &req)
+	if errors.Is(err, services.ErrCalendarURLBlocked) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Calendar URL is not allowed"})
+		return
+	}
+	if errors.Is(err, services.ErrInvalidCalendar) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid calendar"})
+		return
+	}
+	if errors.Is(err, services.ErrCalendarFetch) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to fetch calendar"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save calendar"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"calendar": calendar})
+}
+
+// 上传ICS文件，表单字段为file，可选name
+func (h *CalendarHandler) UploadCalendar(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Calendar file is required"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read calendar file"})
+		return
+	}
+	defer file.Close()
+
+	calendar, err := h.calendarService.UploadCalendar(userID.(uint), c.PostForm("name"), file)
+	if errors.Is(err, services.ErrInvalidCalendar) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid calendar file: " + err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save calendar"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"calendar": calendar})
+}
+
+func (h *CalendarHandler) DeleteCalendar(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	calendarID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid calendar ID"})
+		return
+	}
+
+	// 日历删除后不再同步，先移除已导入的会议；移除失败时保留日历，可以重试删除。
+	// 会议ID以日历ID开头，只会匹配该用户自己的记录
+	if err := h.meetingService.RemoveCalendarMeetings(c.Request.Context(), userID.(uint), uint(calendarID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove calendar meetings"})
+		return
+	}
+
+	err = h.calendarService.DeleteCalendar(userID.(uint), uint(calendarID))
+	if errors.Is(err, services.ErrCalendarNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete calendar"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Calendar deleted"})
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"myvault-backend/internal/models"
+	"myvault-backend/internal/services"
+	"myvault-backend/pkg/gitea"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GiteaHandler struct {
+	giteaService GiteaService
+	userService  UserService
+}
+
+type GiteaService interface {
+	GetUser(baseURL, accessToken string) (*gitea.User, error)
+}
+
+func NewGiteaHandler(giteaService GiteaService, userService UserService) *GiteaHandler {
+	return &GiteaHandler{
+		giteaService: giteaService,
+		userService:  userService,
+	}
+}
+
+// 使用访问令牌绑定Gitea或Forgejo账号
+func (h *GiteaHandler) ConnectGitea(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.ConnectGiteaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 验证令牌并获取Gitea用户信息
+	giteaUser, err := h.giteaService.GetUser(req.BaseURL, req.AccessToken)
+	if errors.Is(err, services.ErrBaseURLBlocked) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Base URL is not allowed"})
+		return
+	}
+	if errors.Is(err, services.ErrGiteaURLRequired) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Gitea base URL is required"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to verify Gitea token"})
+		return
+	}
+
+	user, err := h.userService.ConnectGitea(userID.(uint), giteaUser.Login, giteaUser.Email, req.BaseURL, req.AccessToken)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to connect Gitea account"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
+
+func (h *GiteaHandler) DisconnectGitea(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	user, err := h.userService.DisconnectGitea(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disconnect Gitea account"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
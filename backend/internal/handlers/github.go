@@ -66,6 +66,7 @@ func (h *GithubHandler) GithubCallback(c *gin.Context) {
 		githubUser.Login,
 		githubUser.Email,
 		githubUser.AvatarURL,
+		accessToken,
 	)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"myvault-backend/internal/models"
+	"myvault-backend/internal/services"
+	"myvault-backend/pkg/gitlab"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GitlabHandler struct {
+	gitlabService GitlabService
+	userService   UserService
+}
+
+type GitlabService interface {
+	GetUser(baseURL, accessToken string) (*gitlab.User, error)
+}
+
+func NewGitlabHandler(gitlabService GitlabService, userService UserService) *GitlabHandler {
+	return &GitlabHandler{
+		gitlabService: gitlabService,
+		userService:   userService,
+	}
+}
+
+// 使用个人访问令牌或OAuth令牌绑定GitLab账号
+func (h *GitlabHandler) ConnectGitlab(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.ConnectGitlabRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 验证令牌并获取GitLab用户信息
+	gitlabUser, err := h.gitlabService.GetUser(req.BaseURL, req.AccessToken)
+	if errors.Is(err, services.ErrBaseURLBlocked) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Base URL is not allowed"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to verify GitLab token"})
+		return
+	}
+
+	email := gitlabUser.Email
+	if email == "" {
+		email = gitlabUser.PublicEmail
+	}
+
+	user, err := h.userService.ConnectGitlab(userID.(uint), gitlabUser.Username, email, req.BaseURL, req.AccessToken)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to connect GitLab account"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
+
+func (h *GitlabHandler) DisconnectGitlab(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	user, err := h.userService.DisconnectGitlab(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disconnect GitLab account"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"myvault-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RepositoryHandler struct {
+	repositoryService RepositoryService
+}
+
+type RepositoryService interface {
+	GetUserRepositories(userID uint) ([]models.Repository, error)
+	UpdateRepository(userID, repoID uint, req *models.UpdateRepositoryRequest) (*models.Repository, error)
+}
+
+func NewRepositoryHandler(repositoryService RepositoryService) *RepositoryHandler {
+	return &RepositoryHandler{
+		repositoryService: repositoryService,
+	}
+}
+
+func (h *RepositoryHandler) GetRepositories(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	repos, err := h.repositoryService.GetUserRepositories(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get repositories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"repositories": repos,
+		"total":        len(repos),
+	})
+}
+
+func (h *RepositoryHandler) UpdateRepository(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	repoID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid repository ID"})
+		return
+	}
+
+	var req models.UpdateRepositoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	repo, err := h.repositoryService.UpdateRepository(userID.(uint), uint(repoID), &req)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Repository not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"repository": repo})
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"myvault-backend/internal/models"
+	"myvault-backend/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RollupHandler struct {
+	rollupService RollupService
+}
+
+type RollupService interface {
+	GetWeekSummary(ctx context.Context, userID uint, isoWeek string) (*models.PeriodSummary, error)
+	GetMonthSummary(ctx context.Context, userID uint, month string) (*models.PeriodSummary, error)
+	GetYearSummary(ctx context.Context, userID uint, year string) (*models.PeriodSummary, error)
+}
+
+func NewRollupHandler(rollupService RollupService) *RollupHandler {
+	return &RollupHandler{
+		rollupService: rollupService,
+	}
+}
+
+// 周汇总，如 /summaries/week/2024-W05
+func (h *RollupHandler) GetWeekSummary(c *gin.Context) {
+	h.getSummary(c, c.Param("isoWeek"), h.rollupService.GetWeekSummary)
+}
+
+// 月汇总，如 /summaries/month/2024-05
+func (h *RollupHandler) GetMonthSummary(c *gin.Context) {
+	h.getSummary(c, c.Param("month"), h.rollupService.GetMonthSummary)
+}
+
+// 年度汇总，如 /summaries/year/2024
+func (h *RollupHandler) GetYearSummary(c *gin.Context) {
+	h.getSummary(c, c.Param("year"), h.rollupService.GetYearSummary)
+}
+
+func (h *RollupHandler) getSummary(c *gin.Context, period string, get func(context.Context, uint, string) (*models.PeriodSummary, error)) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	summary, err := get(c.Request.Context(), userID.(uint), period)
+	if errors.Is(err, services.ErrInvalidPeriod) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period"})
+		return
+	}
+	if errors.Is(err, services.ErrPeriodNotStarted) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Period has not started"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate summary"})
+		return
+	}
+
+	// 摘要在后台生成，完成前返回202和保存的旧摘要
+	if summary.Status == models.SummaryPending || summary.Status == models.SummaryGenerating {
+		c.JSON(http.StatusAccepted, gin.H{"summary": summary})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"summary": summary})
+}
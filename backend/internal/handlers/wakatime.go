@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"myvault-backend/internal/models"
+	"myvault-backend/internal/services"
+	"myvault-backend/pkg/wakatime"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WakatimeHandler struct {
+	wakatimeService WakatimeService
+	userService     UserService
+}
+
+type WakatimeService interface {
+	GetCurrentUser(baseURL, apiKey string) (*wakatime.User, error)
+}
+
+func NewWakatimeHandler(wakatimeService WakatimeService, userService UserService) *WakatimeHandler {
+	return &WakatimeHandler{
+		wakatimeService: wakatimeService,
+		userService:     userService,
+	}
+}
+
+// 使用API Key绑定WakaTime或Wakapi账号
+func (h *WakatimeHandler) ConnectWakatime(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.ConnectWakatimeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 验证API Key
+	wakatimeUser, err := h.wakatimeService.GetCurrentUser(req.BaseURL, req.APIKey)
+	if errors.Is(err, services.ErrBaseURLBlocked) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Base URL is not allowed"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to verify WakaTime API key"})
+		return
+	}
+
+	user, err := h.userService.ConnectWakatime(userID.(uint), wakatimeUser.Username, req.BaseURL, req.APIKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to connect WakaTime account"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
+
+func (h *WakatimeHandler) DisconnectWakatime(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	user, err := h.userService.DisconnectWakatime(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disconnect WakaTime account"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"myvault-backend/internal/models"
+	"myvault-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 请求体的大小上限
+const maxWebhookBody = 1 << 20
+
+type WebhookHandler struct {
+	webhookService WebhookService
+}
+
+type WebhookService interface {
+	GetUserWebhooks(userID uint) ([]models.Webhook, error)
+	CreateWebhook(userID uint, req *models.CreateWebhookRequest) (*models.Webhook, string, error)
+	RotateWebhook(userID, webhookID uint) (*models.Webhook, string, error)
+	DeleteWebhook(userID, webhookID uint) error
+	VerifyWebhook(token, signature string, body []byte) (*models.Webhook, error)
+	ReceiveEvent(ctx context.Context, webhook *models.Webhook, event *models.WebhookEvent) (*models.Activity, error)
+}
+
+func NewWebhookHandler(webhookService WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	webhooks, err := h.webhookService.GetUserWebhooks(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get webhooks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"webhooks": webhooks,
+		"total":    len(webhooks),
+	})
+}
+
+// 创建webhook，secret只在创建和轮换时返回
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook, secret, err := h.webhookService.CreateWebhook(userID.(uint), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"webhook": webhook, "secret": secret})
+}
+
+func (h *WebhookHandler) RotateWebhook(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	webhookID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+
+	webhook, secret, err := h.webhookService.RotateWebhook(userID.(uint), uint(webhookID))
+	if errors.Is(err, services.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhook": webhook, "secret": secret})
+}
+
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	webhookID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+
+	err = h.webhookService.DeleteWebhook(userID.(uint), uint(webhookID))
+	if errors.Is(err, services.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted"})
+}
+
+// 接收外部工具的事件，不需要登录，通过地址中的token和X-MyVault-Signature签名认证
+func (h *WebhookHandler) ReceiveEvent(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookBody))
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+		return
+	}
+
+	webhook, err := h.webhookService.VerifyWebhook(c.Param("token"), c.GetHeader("X-MyVault-Signature"), body)
+	if errors.Is(err, services.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+	if errors.Is(err, services.ErrInvalidSignature) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify webhook"})
+		return
+	}
+
+	// 签名校验需要原始请求体，校验后再解析
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	var event models.WebhookEvent
+	if err := c.ShouldBindJSON(&event); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	activity, err := h.webhookService.ReceiveEvent(c.Request.Context(), webhook, &event)
+	if errors.Is(err, services.ErrStaleEvent) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Event timestamp is outside the allowed window"})
+		return
+	}
+	if errors.Is(err, services.ErrLockTimeout) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Activity is being updated, please retry"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save event"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"activity_id": activity.ID})
+}
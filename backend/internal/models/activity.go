@@ -7,21 +7,48 @@ import (
 )
 
 type Activity struct {
-	ID           uint              `json:"id" gorm:"primaryKey"`
-	UserID       uint              `json:"user_id" gorm:"not null"`
-	Date         time.Time         `json:"date" gorm:"not null"`
-	Summary      string            `json:"summary"`
-	AIGenerated  bool              `json:"ai_generated" gorm:"default:false"`
-	HasActivity  bool              `json:"has_activity" gorm:"default:false"`
-	CommitCount  int               `json:"commit_count" gorm:"default:0"`
-	TotalTime    int               `json:"total_time" gorm:"default:0"` // 分钟
-	DataSources  []DataSource      `json:"data_sources" gorm:"foreignKey:ActivityID"`
-	Commits      []Commit          `json:"commits" gorm:"foreignKey:ActivityID"`
-	CreatedAt    time.Time         `json:"created_at"`
-	UpdatedAt    time.Time         `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt    `json:"-" gorm:"index"`
+	ID                 uint           `json:"id" gorm:"primaryKey"`
+	UserID             uint           `json:"user_id" gorm:"not null;uniqueIndex:idx_activities_user_date"`
+	Date               time.Time      `json:"date" gorm:"not null;uniqueIndex:idx_activities_user_date"` // 每个用户每天只有一条活动
+	Summary            string         `json:"summary"`
+	AIGenerated        bool           `json:"ai_generated" gorm:"default:false"`
+	SummaryStatus      string         `json:"summary_status" gorm:"size:20;index"` // 为空表示该字段加入前生成的记录
+	SummaryError       string         `json:"summary_error,omitempty" gorm:"type:text"`
+	SummaryAttempts    int            `json:"summary_attempts" gorm:"default:0"`
+	SummaryFingerprint string         `json:"-" gorm:"size:64"` // 生成摘要时当天记录和提示词的指纹
+	HasActivity        bool           `json:"has_activity" gorm:"default:false"`
+	CommitCount        int            `json:"commit_count" gorm:"default:0"`
+	PullRequestCount   int            `json:"pull_request_count" gorm:"default:0"`
+	ReviewCount        int            `json:"review_count" gorm:"default:0"`
+	IssueCount         int            `json:"issue_count" gorm:"default:0"`
+	TotalTime          int            `json:"total_time" gorm:"default:0"` // 分钟
+	TimeSource         string         `json:"time_source"`                 // measured, estimated, manual，没有时长时为空
+	DataSources        []DataSource   `json:"data_sources" gorm:"foreignKey:ActivityID"`
+	Commits            []Commit       `json:"commits" gorm:"foreignKey:ActivityID"`
+	PullRequests       []PullRequest  `json:"pull_requests" gorm:"foreignKey:ActivityID"`
+	Reviews            []Review       `json:"reviews" gorm:"foreignKey:ActivityID"`
+	IssueEvents        []IssueEvent   `json:"issue_events" gorm:"foreignKey:ActivityID"`
+	ManualEntries      []ManualEntry  `json:"manual_entries" gorm:"foreignKey:ActivityID"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// 摘要由后台worker异步生成
+const (
+	SummaryPending    = "pending"    // 等待生成
+	SummaryGenerating = "generating" // 正在生成
+	SummaryDone       = "done"
+	SummaryFailed     = "failed"  // 生成失败，未超过重试次数时会自动重试
+	SummarySkipped    = "skipped" // 当天没有活动，不需要生成
+)
+
+const (
+	TimeMeasured  = "measured"  // 来自WakaTime等计时工具
+	TimeEstimated = "estimated" // 根据提交时间估算
+	TimeManual    = "manual"    // 只有会议和手动记录的时长
+)
+
 type DataSource struct {
 	ID         uint      `json:"id" gorm:"primaryKey"`
 	ActivityID uint      `json:"activity_id" gorm:"not null"`
@@ -31,33 +58,201 @@ type DataSource struct {
 }
 
 type Commit struct {
-	ID          uint      `json:"id" gorm:"primaryKey"`
-	ActivityID  uint      `json:"activity_id" gorm:"not null"`
-	Hash        string    `json:"hash" gorm:"not null"`
-	Message     string    `json:"message" gorm:"not null"`
-	Repository  string    `json:"repository"`
-	Author      string    `json:"author"`
-	Time        time.Time `json:"time"`
-	Files       int       `json:"files" gorm:"default:0"`
-	Additions   int       `json:"additions" gorm:"default:0"`
-	Deletions   int       `json:"deletions" gorm:"default:0"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ActivityID uint      `json:"activity_id" gorm:"not null"`
+	Hash       string    `json:"hash" gorm:"not null"`
+	Source     string    `json:"source" gorm:"default:github"`
+	Message    string    `json:"message" gorm:"not null"`
+	Repository string    `json:"repository"`
+	Author     string    `json:"author"`
+	Time       time.Time `json:"time"`
+	Files      int       `json:"files" gorm:"default:0"`
+	Additions  int       `json:"additions" gorm:"default:0"`
+	Deletions  int       `json:"deletions" gorm:"default:0"`
+	Partial    bool      `json:"partial" gorm:"default:false"` // 来自推送事件，时间为推送时间且没有行数
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+const (
+	RepositoryTracked = "tracked" // 同步并在时间轴展示
+	RepositoryIgnored = "ignored" // 不同步
+	RepositoryHidden  = "hidden"  // 同步保存，但不在接口返回、计数和AI摘要中出现
+)
+
+// ExternalID用于合并多次同步的结果，如 owner/repo#12:opened
+type PullRequest struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ActivityID uint      `json:"activity_id" gorm:"not null;index"`
+	ExternalID string    `json:"external_id" gorm:"not null;size:255"`
+	Repository string    `json:"repository"`
+	Number     int       `json:"number"`
+	Title      string    `json:"title"`
+	Action     string    `json:"action"` // opened, closed, merged, etc.
+	State      string    `json:"state"`
+	URL        string    `json:"url"`
+	Time       time.Time `json:"time"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type Review struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ActivityID uint      `json:"activity_id" gorm:"not null;index"`
+	ExternalID string    `json:"external_id" gorm:"not null;size:255"`
+	Repository string    `json:"repository"`
+	PullNumber int       `json:"pull_number"`
+	PullTitle  string    `json:"pull_title"`
+	State      string    `json:"state"` // approved, changes_requested, commented
+	URL        string    `json:"url"`
+	Time       time.Time `json:"time"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type IssueEvent struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ActivityID uint      `json:"activity_id" gorm:"not null;index"`
+	ExternalID string    `json:"external_id" gorm:"not null;size:255"`
+	Repository string    `json:"repository"`
+	Number     int       `json:"number"`
+	Title      string    `json:"title"`
+	Action     string    `json:"action"` // opened, closed, reopened, etc.
+	State      string    `json:"state"`
+	URL        string    `json:"url"`
+	Time       time.Time `json:"time"`
+	CreatedAt  time.Time `json:"created_at"`
 }
 
 type Repository struct {
 	ID          uint      `json:"id" gorm:"primaryKey"`
+	UserID      uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_user_source_repo"`
+	Source      string    `json:"source" gorm:"not null;size:50;default:github;uniqueIndex:idx_user_source_repo"`
 	Name        string    `json:"name" gorm:"not null"`
-	FullName    string    `json:"full_name" gorm:"not null"`
+	FullName    string    `json:"full_name" gorm:"not null;size:255;uniqueIndex:idx_user_source_repo"`
 	Description string    `json:"description"`
 	Language    string    `json:"language"`
 	Private     bool      `json:"private" gorm:"default:false"`
+	Fork        bool      `json:"fork" gorm:"default:false"`
+	Archived    bool      `json:"archived" gorm:"default:false"`
+	Status      string    `json:"status" gorm:"not null;size:20;default:tracked"`
 	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// 手动记录，如设计文档、会议、指导等没有提交的工作。同步时不会被覆盖
+type ManualEntry struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ActivityID uint      `json:"activity_id" gorm:"not null;index"`
+	UserID     uint      `json:"user_id" gorm:"not null;index"`
+	Title      string    `json:"title" gorm:"not null"`
+	Body       string    `json:"body" gorm:"type:text"`     // Markdown
+	Duration   int       `json:"duration" gorm:"default:0"` // 分钟
+	Tags       []string  `json:"tags" gorm:"serializer:json;type:text"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// 日历，订阅的ICS地址或上传的ICS文件，同步时将其中的会议导入时间轴
+type Calendar struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	Name      string    `json:"name" gorm:"not null"`
+	URL       string    `json:"url"`                    // 订阅地址，上传的文件为空
+	Content   string    `json:"-" gorm:"type:longtext"` // 上传的ICS内容
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// 接收外部工具（CI、Jira自动化、脚本等）事件的webhook。
+// Token出现在接收地址 /api/hooks/:token 中，Secret用于校验请求体的HMAC签名，只在创建和轮换时返回
+type Webhook struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	Name       string     `json:"name" gorm:"not null"`
+	Token      string     `json:"token" gorm:"not null;size:64;uniqueIndex"`
+	Secret     string     `json:"-" gorm:"not null"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// 周、月、年的汇总摘要，由每日摘要逐级生成。保存后再次查看不需要调用模型，
+// 下级摘要或统计变化时才重新生成
+type PeriodSummary struct {
+	ID               uint             `json:"id" gorm:"primaryKey"`
+	UserID           uint             `json:"user_id" gorm:"not null;uniqueIndex:idx_user_period"`
+	PeriodType       string           `json:"period_type" gorm:"not null;size:10;uniqueIndex:idx_user_period"` // week, month, year
+	Period           string           `json:"period" gorm:"not null;size:10;uniqueIndex:idx_user_period"`      // 如 2024-W05、2024-05、2024
+	StartDate        time.Time        `json:"start_date"`
+	EndDate          time.Time        `json:"end_date"` // 包含当天
+	Summary          string           `json:"summary" gorm:"type:text"`
+	AIGenerated      bool             `json:"ai_generated" gorm:"default:false"`
+	Status           string           `json:"status" gorm:"size:20;index"` // 与Activity的摘要状态相同，为空表示该字段加入前生成的记录
+	Error            string           `json:"error,omitempty" gorm:"type:text"`
+	ActiveDays       int              `json:"active_days" gorm:"default:0"`
+	CommitCount      int              `json:"commit_count" gorm:"default:0"`
+	PullRequestCount int              `json:"pull_request_count" gorm:"default:0"`
+	ReviewCount      int              `json:"review_count" gorm:"default:0"`
+	IssueCount       int              `json:"issue_count" gorm:"default:0"`
+	TotalTime        int              `json:"total_time" gorm:"default:0"` // 分钟
+	Repositories     []RepositoryStat `json:"repositories" gorm:"serializer:json;type:text"`
+	Fingerprint      string           `json:"-" gorm:"size:64"`
+	CreatedAt        time.Time        `json:"created_at"`
+	UpdatedAt        time.Time        `json:"updated_at"`
+}
+
+const (
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+	PeriodYear  = "year"
+)
+
+// 某个仓库在一段时间内的提交统计，隐藏的仓库不计入
+type RepositoryStat struct {
+	Repository string `json:"repository"`
+	Commits    int    `json:"commits"`
+	Additions  int    `json:"additions"`
+	Deletions  int    `json:"deletions"`
+}
+
+// WebhookEvent 是webhook接收的事件，按timestamp所在的日期归入当天的活动
+type WebhookEvent struct {
+	ID        string                 `json:"id" binding:"max=255"`            // 可选，重复发送同一id的事件会覆盖之前的记录，未提供时按事件内容去重
+	Timestamp time.Time              `json:"timestamp" binding:"required"`    // 与服务器时间相差不能超过5分钟
+	Type      string                 `json:"type" binding:"required,max=100"` // 如 ci.build、jira.issue
+	Title     string                 `json:"title" binding:"required,max=255"`
+	URL       string                 `json:"url" binding:"omitempty,url"`
+	Metadata  map[string]interface{} `json:"metadata"`
 }
 
+type CreateWebhookRequest struct {
+	Name string `json:"name" binding:"required,max=255"`
+}
+
+// 在某一天添加手动记录，当天没有活动时会创建
 type ActivityRequest struct {
-	Date time.Time `json:"date" binding:"required"`
+	Date     time.Time `json:"date" binding:"required"`
+	Title    string    `json:"title" binding:"required,max=255"`
+	Body     string    `json:"body"`
+	Duration int       `json:"duration" binding:"min=0"`
+	Tags     []string  `json:"tags"`
+}
+
+type UpdateManualEntryRequest struct {
+	Title    *string   `json:"title" binding:"omitempty,min=1,max=255"`
+	Body     *string   `json:"body"`
+	Duration *int      `json:"duration" binding:"omitempty,min=0"`
+	Tags     *[]string `json:"tags"`
+}
+
+type UpdateRepositoryRequest struct {
+	Status string `json:"status" binding:"required,oneof=tracked ignored hidden"`
+}
+
+// 订阅ICS地址，支持webcal://
+type SubscribeCalendarRequest struct {
+	Name string `json:"name" binding:"max=255"`
+	URL  string `json:"url" binding:"required,url"`
 }
 
 type SyncRequest struct {
 	Force bool `json:"force" binding:"omitempty"`
-}
\ No newline at end of file
+}
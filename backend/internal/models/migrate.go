@@ -10,6 +10,15 @@ func AutoMigrate(db *gorm.DB) error {
 		&Activity{},
 		&DataSource{},
 		&Commit{},
+		&PullRequest{},
+		&Review{},
+		&IssueEvent{},
+		&ManualEntry{},
+		&Calendar{},
+		&Webhook{},
+		&PeriodSummary{},
 		&Repository{},
+		&SyncCursor{},
+		&RepositoryCursor{},
 	)
 }
\ No newline at end of file
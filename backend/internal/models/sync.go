@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+)
+
+// 每个用户在每个数据源上的同步游标
+type SyncCursor struct {
+	ID          uint               `json:"id" gorm:"primaryKey"`
+	UserID      uint               `json:"user_id" gorm:"not null;uniqueIndex:idx_user_source"`
+	Source      string             `json:"source" gorm:"not null;size:50;uniqueIndex:idx_user_source"`
+	LastSyncAt  *time.Time         `json:"last_sync_at"` // 为空表示尚未成功同步过
+	RepoCursors []RepositoryCursor `json:"repo_cursors" gorm:"foreignKey:SyncCursorID"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// 单个仓库最后一次看到的提交
+type RepositoryCursor struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	SyncCursorID uint      `json:"sync_cursor_id" gorm:"not null;uniqueIndex:idx_cursor_repo"`
+	Repository   string    `json:"repository" gorm:"not null;size:255;uniqueIndex:idx_cursor_repo"`
+	LastSHA      string    `json:"last_sha"`
+	LastCommitAt time.Time `json:"last_commit_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+const (
+	SyncJobQueued    = "queued"
+	SyncJobRunning   = "running"
+	SyncJobSucceeded = "succeeded"
+	SyncJobFailed    = "failed"
+)
+
+type SyncProgress struct {
+	ReposTotal     int           `json:"repos_total"`
+	ReposProcessed int           `json:"repos_processed"`
+	DaysTotal      int           `json:"days_total"`
+	DaysProcessed  int           `json:"days_processed"`
+	Failures       []SyncFailure `json:"failures,omitempty"`
+}
+
+// 单个仓库同步失败的原因
+type SyncFailure struct {
+	Repository string `json:"repository"`
+	Error      string `json:"error"`
+}
+
+// 异步同步任务，保存在Redis中
+type SyncJob struct {
+	ID         string       `json:"id"`
+	UserID     uint         `json:"user_id"`
+	Force      bool         `json:"force"`
+	State      string       `json:"state"`
+	Progress   SyncProgress `json:"progress"`
+	Error      string       `json:"error,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+	StartedAt  *time.Time   `json:"started_at,omitempty"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+	// 排队、重新排队和运行期间定期更新，长时间没有更新的任务视为已丢失
+	HeartbeatAt time.Time `json:"heartbeat_at"`
+}
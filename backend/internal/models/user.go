@@ -15,6 +15,27 @@ type User struct {
 	GithubUsername string `json:"github_username"`
 	GithubID       string `json:"github_id"`
 	AccessToken    string `json:"-"`
+	// GitLab绑定信息，BaseURL为空时使用配置的默认实例
+	GitlabUsername string `json:"gitlab_username"`
+	GitlabEmail    string `json:"gitlab_email"`
+	GitlabBaseURL  string `json:"gitlab_base_url"`
+	GitlabToken    string `json:"-"`
+	// Gitea/Forgejo绑定信息，BaseURL为空时使用配置的默认实例
+	GiteaUsername string `json:"gitea_username"`
+	GiteaEmail    string `json:"gitea_email"`
+	GiteaBaseURL  string `json:"gitea_base_url"`
+	GiteaToken    string `json:"-"`
+	// WakaTime兼容服务（含Wakapi）的绑定信息，BaseURL为空时使用配置的默认地址
+	WakatimeUsername string `json:"wakatime_username"`
+	WakatimeBaseURL  string `json:"wakatime_base_url"`
+	WakatimeAPIKey   string `json:"-"`
+	// 本地git仓库路径，需位于服务端配置的LOCAL_GIT_ROOTS下的<用户ID>目录之中；邮箱为空时使用账号邮箱
+	LocalRepoPaths []string `json:"local_repo_paths" gorm:"serializer:json;type:text"`
+	LocalGitEmail  string   `json:"local_git_email"`
+	// 同步设置。gorm创建记录时会用default替换false，IncludeForks不设default，由创建用户时显式设为true
+	IncludeForks    bool `json:"include_forks"`
+	IncludeArchived bool `json:"include_archived" gorm:"default:false"`
+	AllBranches     bool `json:"all_branches" gorm:"default:false"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
 	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
@@ -32,6 +53,26 @@ type LoginRequest struct {
 }
 
 type UpdateUserRequest struct {
-	Username string `json:"username" binding:"omitempty,min=3,max=20"`
-	Avatar   string `json:"avatar"`
+	Username        string `json:"username" binding:"omitempty,min=3,max=20"`
+	Avatar          string `json:"avatar"`
+	IncludeForks    *bool  `json:"include_forks"`
+	IncludeArchived *bool  `json:"include_archived"`
+	AllBranches     *bool  `json:"all_branches"`
+	LocalRepoPaths  *[]string `json:"local_repo_paths"`
+	LocalGitEmail   *string   `json:"local_git_email" binding:"omitempty,email"`
+}
+
+type ConnectGitlabRequest struct {
+	AccessToken string `json:"access_token" binding:"required"`
+	BaseURL     string `json:"base_url" binding:"omitempty,url"`
+}
+
+type ConnectGiteaRequest struct {
+	AccessToken string `json:"access_token" binding:"required"`
+	BaseURL     string `json:"base_url" binding:"omitempty,url"`
+}
+
+type ConnectWakatimeRequest struct {
+	APIKey  string `json:"api_key" binding:"required"`
+	BaseURL string `json:"base_url" binding:"omitempty,url"`
 }
\ No newline at end of file
@@ -1,8 +1,13 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"myvault-backend/internal/models"
+	"myvault-backend/pkg/ai"
 	"strings"
 	"time"
 
@@ -10,17 +15,53 @@ import (
 	"gorm.io/gorm"
 )
 
+// 首次同步时回溯的天数
+const defaultSyncDays = 30
+
+const (
+	// 单日重写的锁，超时后自动释放
+	dayLockTTL = time.Minute
+	// 等待其他写入完成的最长时间
+	dayLockWait = 30 * time.Second
+)
+
+var ErrNoSourceConnected = errors.New("用户未绑定任何数据源")
+
+// 某一天需要写入的全部记录
+type ActivityData struct {
+	Commits      []models.Commit
+	DataSources  []models.DataSource
+	PullRequests []models.PullRequest
+	Reviews      []models.Review
+	IssueEvents  []models.IssueEvent
+	// 手动记录不随同步重写，由CreateOrUpdateActivity从数据库加载
+	ManualEntries []models.ManualEntry
+}
+
+func (d *ActivityData) empty() bool {
+	if ct := d.codingTime(); ct != nil && ct.TotalSeconds > 0 {
+		return false
+	}
+	return len(d.Commits) == 0 && len(d.PullRequests) == 0 && len(d.Reviews) == 0 && len(d.IssueEvents) == 0 && len(d.ManualEntries) == 0 && len(d.meetings()) == 0 && len(d.webhookEvents()) == 0
+}
+
 type ActivityService struct {
-	db        *gorm.DB
-	redis     *redis.Client
-	aiService *AIService
+	db                *gorm.DB
+	redis             *redis.Client
+	aiService         *AIService
+	repositoryService *RepositoryService
+	sources           *SourceRegistry
+	estimator         *TimeEstimator
 }
 
-func NewActivityService(db *gorm.DB, redis *redis.Client, aiService *AIService) *ActivityService {
+func NewActivityService(db *gorm.DB, redis *redis.Client, aiService *AIService, repositoryService *RepositoryService, sources *SourceRegistry, estimator *TimeEstimator) *ActivityService {
 	return &ActivityService{
-		db:        db,
-		redis:     redis,
-		aiService: aiService,
+		db:                db,
+		redis:             redis,
+		aiService:         aiService,
+		repositoryService: repositoryService,
+		sources:           sources,
+		estimator:         estimator,
 	}
 }
 
@@ -30,6 +71,10 @@ func (s *ActivityService) GetUserActivities(userID uint, limit int, offset int)
 	query := s.db.Where("user_id = ?", userID).
 		Preload("Commits").
 		Preload("DataSources").
+		Preload("PullRequests").
+		Preload("Reviews").
+		Preload("IssueEvents").
+		Preload("ManualEntries").
 		Order("date DESC")
 
 	if limit > 0 {
@@ -44,6 +89,14 @@ func (s *ActivityService) GetUserActivities(userID uint, limit int, offset int)
 		return nil, err
 	}
 
+	visible := make([]*models.Activity, len(activities))
+	for i := range activities {
+		visible[i] = &activities[i]
+	}
+	if err := s.hideRepositories(userID, visible...); err != nil {
+		return nil, err
+	}
+
 	return activities, nil
 }
 
@@ -53,123 +106,863 @@ func (s *ActivityService) GetActivityByID(userID, activityID uint) (*models.Acti
 	if err := s.db.Where("id = ? AND user_id = ?", activityID, userID).
 		Preload("Commits").
 		Preload("DataSources").
+		Preload("PullRequests").
+		Preload("Reviews").
+		Preload("IssueEvents").
+		Preload("ManualEntries").
 		First(&activity).Error; err != nil {
 		return nil, err
 	}
 
+	if err := s.hideRepositories(userID, &activity); err != nil {
+		return nil, err
+	}
+
 	return &activity, nil
 }
 
-func (s *ActivityService) CreateOrUpdateActivity(userID uint, date time.Time, commits []models.Commit, dataSources []models.DataSource) (*models.Activity, error) {
+// 重写某一天的活动记录，调用方需通过withDayLock持有当天的锁
+func (s *ActivityService) CreateOrUpdateActivity(ctx context.Context, userID uint, date time.Time, data *ActivityData) (*models.Activity, error) {
 	// 检查是否已存在该日期的活动
 	var activity models.Activity
 	dateStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
 	dateEnd := dateStart.Add(24 * time.Hour)
 
-	err := s.db.Where("user_id = ? AND date >= ? AND date < ?", userID, dateStart, dateEnd).
+	// 删除和重新写入在同一事务中完成，失败时保留原有记录
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND date >= ? AND date < ?", userID, dateStart, dateEnd).
+			First(&activity).Error
+
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+
+		// 手动记录不会被重写，加载后参与统计和摘要
+		data.ManualEntries = nil
+		if err == nil {
+			if err := tx.Where("activity_id = ?", activity.ID).Order("created_at").Find(&data.ManualEntries).Error; err != nil {
+				return err
+			}
+		}
+
+		// 如果不存在，创建新的活动记录
+		if err == gorm.ErrRecordNotFound {
+			activity = models.Activity{
+				UserID: userID,
+				Date:   dateStart,
+			}
+			s.setActivityCounts(&activity, data)
+			resetSummaryStatus(&activity, data)
+			if err := tx.Create(&activity).Error; err != nil {
+				return err
+			}
+		} else {
+			// 更新现有活动
+			s.setActivityCounts(&activity, data)
+			resetSummaryStatus(&activity, data)
+			if err := tx.Save(&activity).Error; err != nil {
+				return err
+			}
+
+			// 删除旧的记录
+			for _, model := range []interface{}{&models.Commit{}, &models.DataSource{}, &models.PullRequest{}, &models.Review{}, &models.IssueEvent{}} {
+				if err := tx.Where("activity_id = ?", activity.ID).Delete(model).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		// 添加新的记录
+		if err := createDayRecords(tx, activity.ID, data.Commits, func(c *models.Commit, id uint) { c.ActivityID = id }); err != nil {
+			return err
+		}
+		if err := createDayRecords(tx, activity.ID, data.DataSources, func(d *models.DataSource, id uint) { d.ActivityID = id }); err != nil {
+			return err
+		}
+		if err := createDayRecords(tx, activity.ID, data.PullRequests, func(pr *models.PullRequest, id uint) { pr.ActivityID = id }); err != nil {
+			return err
+		}
+		if err := createDayRecords(tx, activity.ID, data.Reviews, func(r *models.Review, id uint) { r.ActivityID = id }); err != nil {
+			return err
+		}
+		return createDayRecords(tx, activity.ID, data.IssueEvents, func(i *models.IssueEvent, id uint) { i.ActivityID = id })
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// AI摘要由后台worker生成，记录写入后再加入队列
+	if activity.SummaryStatus == models.SummaryPending {
+		if err := enqueueSummary(ctx, s.redis, activity.ID); err != nil {
+			// 保持pending状态，worker启动时会重新加入队列
+			log.Printf("Failed to enqueue summary for activity %d: %v", activity.ID, err)
+		}
+	}
+
+	// 重新加载活动数据
+	s.db.Where("id = ?", activity.ID).
 		Preload("Commits").
 		Preload("DataSources").
-		First(&activity).Error
+		Preload("PullRequests").
+		Preload("Reviews").
+		Preload("IssueEvents").
+		Preload("ManualEntries").
+		First(&activity)
 
-	if err != nil && err != gorm.ErrRecordNotFound {
-		return nil, err
+	return &activity, nil
+}
+
+// 同一用户同一天的读取、合并和重写需要串行执行，否则并发写入会互相覆盖
+func (s *ActivityService) withDayLock(ctx context.Context, userID uint, date time.Time, fn func() error) error {
+	key := fmt.Sprintf("myvault:activity:lock:%d:%s", userID, date.In(time.Local).Format("2006-01-02"))
+	lock, err := waitForLock(ctx, s.redis, key, dayLockTTL, dayLockWait)
+	if err != nil {
+		return err
 	}
+	defer lock.Release(context.Background())
 
-	// 如果不存在，创建新的活动记录
-	if err == gorm.ErrRecordNotFound {
-		activity = models.Activity{
-			UserID:      userID,
-			Date:        dateStart,
-			HasActivity: len(commits) > 0,
-			CommitCount: len(commits),
+	return fn()
+}
+
+// 数据变化后需要重新生成摘要，没有活动的日期直接跳过。生成前保留旧摘要供展示
+func resetSummaryStatus(activity *models.Activity, data *ActivityData) {
+	if data.empty() {
+		activity.Summary = "今日无编程活动"
+		activity.AIGenerated = false
+		activity.SummaryStatus = models.SummarySkipped
+		activity.SummaryFingerprint = ""
+	} else {
+		activity.SummaryStatus = models.SummaryPending
+	}
+	activity.SummaryError = ""
+	activity.SummaryAttempts = 0
+}
+
+// 将记录关联到活动并批量写入
+func createDayRecords[T any](db *gorm.DB, activityID uint, records []T, link func(*T, uint)) error {
+	if len(records) == 0 {
+		return nil
+	}
+	for i := range records {
+		link(&records[i], activityID)
+	}
+	return db.Create(&records).Error
+}
+
+func (s *ActivityService) setActivityCounts(activity *models.Activity, data *ActivityData) {
+	activity.HasActivity = !data.empty()
+	activity.CommitCount = len(data.Commits)
+	activity.PullRequestCount = len(data.PullRequests)
+	activity.ReviewCount = len(data.Reviews)
+	activity.IssueCount = len(data.IssueEvents)
+
+	// 有计时工具的数据时使用实测时长，否则根据提交时间估算
+	activity.TotalTime = 0
+	activity.TimeSource = ""
+	if ct := data.codingTime(); ct != nil && ct.TotalSeconds > 0 {
+		activity.TotalTime = (ct.TotalSeconds + 30) / 60
+		activity.TimeSource = models.TimeMeasured
+	} else if len(data.Commits) > 0 {
+		activity.TotalTime = int(s.estimator.Estimate(data.Commits).Round(time.Minute) / time.Minute)
+		activity.TimeSource = models.TimeEstimated
+	}
+
+	// 日历中的会议和手动记录的时长（文档等）累加到总时长
+	activity.TotalTime += int(meetingTime(data.meetings()).Round(time.Minute) / time.Minute)
+	for _, entry := range data.ManualEntries {
+		activity.TotalTime += entry.Duration
+	}
+	if activity.TimeSource == "" && activity.TotalTime > 0 {
+		activity.TimeSource = models.TimeManual
+	}
+}
+
+func filterHidden(data *ActivityData, hidden map[string]bool) *ActivityData {
+	visible := &ActivityData{ManualEntries: data.ManualEntries}
+	// 编码时长按项目名统计，会议和外部事件与仓库无关，原样保留
+	for _, ds := range data.DataSources {
+		if ds.Type == codingTimeSourceType || ds.Type == calendarSourceType || ds.Type == webhookSourceType {
+			visible.DataSources = append(visible.DataSources, ds)
 		}
-		if err := s.db.Create(&activity).Error; err != nil {
-			return nil, err
+	}
+	for _, commit := range data.Commits {
+		if !hidden[commit.Repository] {
+			visible.Commits = append(visible.Commits, commit)
 		}
-	} else {
-		// 更新现有活动
-		activity.HasActivity = len(commits) > 0
-		activity.CommitCount = len(commits)
-		if err := s.db.Save(&activity).Error; err != nil {
-			return nil, err
+	}
+	for _, pr := range data.PullRequests {
+		if !hidden[pr.Repository] {
+			visible.PullRequests = append(visible.PullRequests, pr)
+		}
+	}
+	for _, review := range data.Reviews {
+		if !hidden[review.Repository] {
+			visible.Reviews = append(visible.Reviews, review)
+		}
+	}
+	for _, issue := range data.IssueEvents {
+		if !hidden[issue.Repository] {
+			visible.IssueEvents = append(visible.IssueEvents, issue)
 		}
+	}
+	return visible
+}
+
+// 返回给前端前移除隐藏仓库的记录，提交汇总和各项计数按剩余的记录重新计算，
+// 数据库中保存的仍是完整数据
+func (s *ActivityService) hideRepositories(userID uint, activities ...*models.Activity) error {
+	hidden, err := s.repositoryService.HiddenRepositories(userID)
+	if err != nil {
+		return err
+	}
+	if len(hidden) == 0 {
+		return nil
+	}
 
-		// 删除旧的提交记录和数据源
-		s.db.Where("activity_id = ?", activity.ID).Delete(&models.Commit{})
-		s.db.Where("activity_id = ?", activity.ID).Delete(&models.DataSource{})
+	for _, activity := range activities {
+		if err := hideActivityRepositories(activity, hidden); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	// 添加新的提交记录
-	for i := range commits {
-		commits[i].ActivityID = activity.ID
+func hideActivityRepositories(activity *models.Activity, hidden map[string]bool) error {
+	// 提交汇总的类型与提交的来源相同，需要在过滤前记录
+	commitSources := make(map[string]bool)
+	for _, commit := range activity.Commits {
+		commitSources[commit.Source] = true
 	}
-	if len(commits) > 0 {
-		if err := s.db.Create(&commits).Error; err != nil {
-			return nil, err
+
+	visible := filterHidden(&ActivityData{
+		Commits:      activity.Commits,
+		PullRequests: activity.PullRequests,
+		Reviews:      activity.Reviews,
+		IssueEvents:  activity.IssueEvents,
+	}, hidden)
+
+	dataSources := make([]models.DataSource, 0, len(activity.DataSources))
+	for _, ds := range activity.DataSources {
+		switch {
+		case commitSources[ds.Type]:
+			var commits []models.Commit
+			for _, commit := range visible.Commits {
+				if commit.Source == ds.Type {
+					commits = append(commits, commit)
+				}
+			}
+			if len(commits) == 0 {
+				continue
+			}
+			summary, err := commitDataSource(ds.Type, commits)
+			if err != nil {
+				return err
+			}
+			ds.Data = summary.Data
+		case isGithubEventSourceType(ds.Type):
+			data, ok, err := filterGithubEventRecords(ds.Data, hidden)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			ds.Data = data
 		}
+		dataSources = append(dataSources, ds)
 	}
 
-	// 添加新的数据源
-	for i := range dataSources {
-		dataSources[i].ActivityID = activity.ID
+	activity.Commits = visible.Commits
+	activity.DataSources = dataSources
+	activity.PullRequests = visible.PullRequests
+	activity.Reviews = visible.Reviews
+	activity.IssueEvents = visible.IssueEvents
+	activity.CommitCount = len(visible.Commits)
+	activity.PullRequestCount = len(visible.PullRequests)
+	activity.ReviewCount = len(visible.Reviews)
+	activity.IssueCount = len(visible.IssueEvents)
+	return nil
+}
+
+// 修改提示词或摘要格式时递增，使已缓存的摘要失效
+const summaryPromptVersion = 1
+
+// 构建当天摘要的请求，记录需要先按sortActivityData排序，相同的数据才会得到相同的提示词
+func buildSummaryRequest(data *ActivityData) *ai.SummaryRequest {
+	// 构建提示词
+	var promptBuilder strings.Builder
+
+	if ct := data.codingTime(); ct != nil && ct.TotalSeconds > 0 {
+		promptBuilder.WriteString(fmt.Sprintf("今日编码时长: %s\n", formatDuration(ct.TotalSeconds)))
+		if len(ct.Projects) > 0 {
+			promptBuilder.WriteString(fmt.Sprintf("项目: %s\n", formatCodingTimeItems(ct.Projects, 5)))
+		}
+		if len(ct.Languages) > 0 {
+			promptBuilder.WriteString(fmt.Sprintf("语言: %s\n", formatCodingTimeItems(ct.Languages, 5)))
+		}
+		promptBuilder.WriteString("\n")
 	}
-	if len(dataSources) > 0 {
-		if err := s.db.Create(&dataSources).Error; err != nil {
-			return nil, err
+
+	if len(data.Commits) > 0 {
+		promptBuilder.WriteString("以下是今日的代码提交记录：\n\n")
+		for _, commit := range data.Commits {
+			promptBuilder.WriteString(fmt.Sprintf("时间: %s\n", commit.Time.Format("15:04")))
+			promptBuilder.WriteString(fmt.Sprintf("仓库: %s\n", commit.Repository))
+			promptBuilder.WriteString(fmt.Sprintf("提交信息: %s\n", commit.Message))
+			promptBuilder.WriteString(fmt.Sprintf("文件数: %d, 新增: %d行, 删除: %d行\n\n",
+				commit.Files, commit.Additions, commit.Deletions))
 		}
 	}
 
-	// 生成AI摘要
-	if len(commits) > 0 {
-		summary, err := s.generateAISummary(commits)
-		if err == nil {
-			activity.Summary = summary
-			activity.AIGenerated = true
-			s.db.Save(&activity)
+	if len(data.PullRequests) > 0 {
+		promptBuilder.WriteString("以下是今日的Pull Request记录：\n\n")
+		for _, pr := range data.PullRequests {
+			promptBuilder.WriteString(fmt.Sprintf("%s %s #%d %s\n", pr.Action, pr.Repository, pr.Number, pr.Title))
 		}
-	} else {
-		activity.Summary = "今日无编程活动"
-		activity.AIGenerated = false
-		s.db.Save(&activity)
+		promptBuilder.WriteString("\n")
 	}
 
-	// 重新加载活动数据
-	s.db.Where("id = ?", activity.ID).
-		Preload("Commits").
-		Preload("DataSources").
-		First(&activity)
+	if len(data.Reviews) > 0 {
+		promptBuilder.WriteString("以下是今日的代码评审记录：\n\n")
+		for _, review := range data.Reviews {
+			promptBuilder.WriteString(fmt.Sprintf("%s %s #%d %s\n", review.State, review.Repository, review.PullNumber, review.PullTitle))
+		}
+		promptBuilder.WriteString("\n")
+	}
 
-	return &activity, nil
+	if len(data.IssueEvents) > 0 {
+		promptBuilder.WriteString("以下是今日的Issue记录：\n\n")
+		for _, issue := range data.IssueEvents {
+			promptBuilder.WriteString(fmt.Sprintf("%s %s #%d %s\n", issue.Action, issue.Repository, issue.Number, issue.Title))
+		}
+		promptBuilder.WriteString("\n")
+	}
+
+	if meetings := data.meetings(); len(meetings) > 0 {
+		promptBuilder.WriteString("以下是今日的会议/日程：\n\n")
+		for _, meeting := range meetings {
+			promptBuilder.WriteString(fmt.Sprintf("%s-%s %s (%s)\n",
+				meeting.Start.Format("15:04"), meeting.End.Format("15:04"), meeting.Title, formatDuration(meeting.Duration*60)))
+		}
+		promptBuilder.WriteString("\n")
+	}
+
+	if events := data.webhookEvents(); len(events) > 0 {
+		promptBuilder.WriteString("以下是今日来自其他工具（CI、任务管理等）的事件：\n\n")
+		for _, event := range events {
+			promptBuilder.WriteString(fmt.Sprintf("%s [%s] %s\n", event.Time.Format("15:04"), event.Type, event.Title))
+		}
+		promptBuilder.WriteString("\n")
+	}
+
+	if len(data.ManualEntries) > 0 {
+		promptBuilder.WriteString("以下是今日的手动记录（没有代码提交的工作）：\n\n")
+		for _, entry := range data.ManualEntries {
+			promptBuilder.WriteString(fmt.Sprintf("标题: %s\n", entry.Title))
+			if entry.Duration > 0 {
+				promptBuilder.WriteString(fmt.Sprintf("时长: %s\n", formatDuration(entry.Duration*60)))
+			}
+			if len(entry.Tags) > 0 {
+				promptBuilder.WriteString(fmt.Sprintf("标签: %s\n", strings.Join(entry.Tags, ", ")))
+			}
+			if entry.Body != "" {
+				promptBuilder.WriteString(fmt.Sprintf("内容:\n%s\n", entry.Body))
+			}
+			promptBuilder.WriteString("\n")
+		}
+	}
+
+	promptBuilder.WriteString("请基于以上信息生成一份简洁的每日编程活动摘要。")
+
+	req := summaryFacts(data)
+	req.Prompt = promptBuilder.String()
+	return req
 }
 
-func (s *ActivityService) generateAISummary(commits []models.Commit) (string, error) {
-	if len(commits) == 0 {
-		return "今日无编程活动", nil
+// 整理当天的统计和要点，供不调用大模型的模板摘要使用
+func summaryFacts(data *ActivityData) *ai.SummaryRequest {
+	req := &ai.SummaryRequest{Title: "今日"}
+
+	if ct := data.codingTime(); ct != nil && ct.TotalSeconds > 0 {
+		req.Stats = append(req.Stats, "编码"+formatDuration(ct.TotalSeconds))
+	}
+	counts := []struct {
+		n    int
+		unit string
+	}{
+		{len(data.Commits), "次提交"},
+		{len(data.PullRequests), "个Pull Request事件"},
+		{len(data.Reviews), "次代码评审"},
+		{len(data.IssueEvents), "个Issue事件"},
+		{len(data.meetings()), "场会议"},
+		{len(data.webhookEvents()), "条外部事件"},
+		{len(data.ManualEntries), "条手动记录"},
+	}
+	for _, count := range counts {
+		if count.n > 0 {
+			req.Stats = append(req.Stats, fmt.Sprintf("%d%s", count.n, count.unit))
+		}
 	}
 
-	// 构建提示词
-	var promptBuilder strings.Builder
-	promptBuilder.WriteString("以下是今日的代码提交记录：\n\n")
+	for _, entry := range data.ManualEntries {
+		req.Highlights = append(req.Highlights, entry.Title)
+	}
+	for _, pr := range data.PullRequests {
+		req.Highlights = append(req.Highlights, fmt.Sprintf("%s %s #%d %s", pr.Action, pr.Repository, pr.Number, pr.Title))
+	}
+	for _, commit := range data.Commits {
+		message, _, _ := strings.Cut(commit.Message, "\n")
+		req.Highlights = append(req.Highlights, fmt.Sprintf("%s: %s", commit.Repository, message))
+	}
+	for _, meeting := range data.meetings() {
+		req.Highlights = append(req.Highlights, fmt.Sprintf("会议: %s", meeting.Title))
+	}
+	for _, event := range data.webhookEvents() {
+		req.Highlights = append(req.Highlights, fmt.Sprintf("%s: %s", event.Type, event.Title))
+	}
+
+	return req
+}
+
+// 判断用户是否绑定了任一已注册的数据源
+func (s *ActivityService) HasConnectedSource(user *models.User) bool {
+	return len(s.sources.Connected(user)) > 0
+}
+
+func (s *ActivityService) SyncActivities(ctx context.Context, userID uint, force bool) error {
+	return s.SyncActivitiesWithProgress(ctx, userID, force, nil)
+}
+
+// onProgress可为nil，每处理完一个仓库或一天都会回调一次。
+// 依次同步用户绑定的每个数据源，某个数据源失败不影响其他数据源
+func (s *ActivityService) SyncActivitiesWithProgress(ctx context.Context, userID uint, force bool, onProgress func(models.SyncProgress)) error {
+	var progress models.SyncProgress
+	report := func() {
+		if onProgress != nil {
+			onProgress(progress)
+		}
+	}
+
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return err
+	}
+
+	sources := s.sources.Connected(&user)
+	if len(sources) == 0 {
+		return ErrNoSourceConnected
+	}
 
+	now := time.Now()
+	sc := &SyncContext{
+		User:     &user,
+		Force:    force,
+		From:     time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local).AddDate(0, 0, -defaultSyncDays),
+		To:       now,
+		progress: &progress,
+		report:   report,
+	}
+
+	var errs []error
+	for _, source := range sources {
+		if err := s.syncSource(ctx, source, sc); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source.Name(), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *ActivityService) syncSource(ctx context.Context, source ActivitySource, sc *SyncContext) error {
+	cursor, err := s.loadSyncCursor(sc.User.ID, source.Name())
+	if err != nil {
+		return err
+	}
+	sc.Cursor = cursor
+
+	failures := len(sc.progress.Failures)
+	batch, abortErr := source.Fetch(sc)
+	if batch == nil {
+		return abortErr
+	}
+
+	// 提交的来源统一由数据源名称决定，汇总DataSource的Type与之一致
+	for day := range batch.commits {
+		for i := range batch.commits[day] {
+			batch.commits[day][i].Source = source.Name()
+		}
+	}
+
+	if err := s.writeSyncBatch(ctx, sc.User.ID, source.Name(), batch, sc.Force, sc.progress, sc.report); err != nil {
+		return err
+	}
+
+	// 有仓库失败时不推进全局游标，下次同步重新获取这些仓库
+	if len(sc.progress.Failures) == failures {
+		to := sc.To
+		cursor.LastSyncAt = &to
+	}
+	if err := s.saveSyncCursor(cursor); err != nil {
+		return err
+	}
+
+	return abortErr
+}
+
+// 只重写受影响的日期。强制同步时只替换source自己的提交，其他数据源的提交保留
+func (s *ActivityService) writeSyncBatch(ctx context.Context, userID uint, source string, batch *SyncBatch, force bool, progress *models.SyncProgress, report func()) error {
+	if err := s.dropPartialCommits(userID, batch); err != nil {
+		return err
+	}
+	dayKeys := batch.days()
+	progress.DaysTotal += len(dayKeys)
+	report()
+
+	for _, day := range dayKeys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		date, err := time.ParseInLocation("2006-01-02", day, time.Local)
+		if err != nil {
+			return err
+		}
+
+		if err := s.withDayLock(ctx, userID, date, func() error {
+			return s.writeSyncDay(ctx, userID, source, batch, day, date, force)
+		}); err != nil {
+			return err
+		}
+
+		progress.DaysProcessed++
+		report()
+	}
+
+	return nil
+}
+
+// 将某一天的同步数据与已有记录合并后写入，调用方需持有当天的锁
+func (s *ActivityService) writeSyncDay(ctx context.Context, userID uint, source string, batch *SyncBatch, day string, date time.Time, force bool) error {
+	// 事件类数据源和PR、review、issue记录始终与已有记录合并，强制同步也不会丢失
+	existingSources, err := getDayRecords[models.DataSource](s.db, "data_sources", userID, date)
+	if err != nil {
+		return err
+	}
+	// 只是被Cover的日期，没有该数据源的旧记录时无需重写，避免为每一天创建空活动
+	if !batch.hasData(day) && !hasDataSource(existingSources, source) {
+		return nil
+	}
+
+	existing, err := getDayRecords[models.Commit](s.db, "commits", userID, date)
+	if err != nil {
+		return err
+	}
+	kept := existing[:0]
+	for _, commit := range existing {
+		if (force && commit.Source == source) || batch.dropped[day][commit.Hash] {
+			continue
+		}
+		kept = append(kept, commit)
+	}
+	existing = kept
+	commits := mergeCommits(existing, batch.commits[day])
+
+	// 按数据源分组提交，重新生成各数据源的汇总
+	var commitSources []string
+	commitsBySource := make(map[string][]models.Commit)
 	for _, commit := range commits {
-		promptBuilder.WriteString(fmt.Sprintf("时间: %s\n", commit.Time.Format("15:04")))
-		promptBuilder.WriteString(fmt.Sprintf("仓库: %s\n", commit.Repository))
-		promptBuilder.WriteString(fmt.Sprintf("提交信息: %s\n", commit.Message))
-		promptBuilder.WriteString(fmt.Sprintf("文件数: %d, 新增: %d行, 删除: %d行\n\n", 
-			commit.Files, commit.Additions, commit.Deletions))
+		if _, ok := commitsBySource[commit.Source]; !ok {
+			commitSources = append(commitSources, commit.Source)
+		}
+		commitsBySource[commit.Source] = append(commitsBySource[commit.Source], commit)
+	}
+	summaryTypes := map[string]bool{source: true}
+	for _, commitSource := range commitSources {
+		summaryTypes[commitSource] = true
 	}
 
-	promptBuilder.WriteString("请基于以上信息生成一份简洁的每日编程活动摘要。")
+	// 没有获取到的记录不在新数据中，先从旧数据中取出，新数据中同id的记录仍然优先
+	incoming := batch.dataSources[day]
+	if len(batch.kept) > 0 {
+		kept, err := keptRecords(existingSources, source, batch.kept)
+		if err != nil {
+			return err
+		}
+		incoming = append(kept, incoming...)
+	}
+
+	dataSources, err := mergeDataSources(existingSources, incoming, summaryTypes)
+	if err != nil {
+		return err
+	}
+
+	summaries := make([]models.DataSource, 0, len(commitSources))
+	for _, commitSource := range commitSources {
+		dataSource, err := commitDataSource(commitSource, commitsBySource[commitSource])
+		if err != nil {
+			return err
+		}
+		summaries = append(summaries, dataSource)
+	}
+	dataSources = append(summaries, dataSources...)
+
+	existingPRs, err := getDayRecords[models.PullRequest](s.db, "pull_requests", userID, date)
+	if err != nil {
+		return err
+	}
+	existingReviews, err := getDayRecords[models.Review](s.db, "reviews", userID, date)
+	if err != nil {
+		return err
+	}
+	existingIssues, err := getDayRecords[models.IssueEvent](s.db, "issue_events", userID, date)
+	if err != nil {
+		return err
+	}
+
+	data := &ActivityData{
+		Commits:     commits,
+		DataSources: dataSources,
+		PullRequests: mergeByExternalID(existingPRs, batch.pullRequests[day], func(pr *models.PullRequest) string {
+			pr.ID, pr.ActivityID = 0, 0
+			return pr.ExternalID
+		}),
+		Reviews: mergeByExternalID(existingReviews, batch.reviews[day], func(review *models.Review) string {
+			review.ID, review.ActivityID = 0, 0
+			return review.ExternalID
+		}),
+		IssueEvents: mergeByExternalID(existingIssues, batch.issueEvents[day], func(issue *models.IssueEvent) string {
+			issue.ID, issue.ActivityID = 0, 0
+			return issue.ExternalID
+		}),
+	}
+
+	_, err = s.CreateOrUpdateActivity(ctx, userID, date, data)
+	return err
+}
+
+func (s *ActivityService) loadSyncCursor(userID uint, source string) (*models.SyncCursor, error) {
+	var cursor models.SyncCursor
+	err := s.db.Where("user_id = ? AND source = ?", userID, source).
+		Preload("RepoCursors").
+		First(&cursor).Error
+
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
 
-	return s.aiService.GenerateSummary(promptBuilder.String())
+	if err == gorm.ErrRecordNotFound {
+		return &models.SyncCursor{UserID: userID, Source: source}, nil
+	}
+
+	return &cursor, nil
 }
 
-func (s *ActivityService) SyncActivities(userID uint, force bool) error {
-	// 这里应该实现从各种数据源同步活动的逻辑
-	// 暂时返回nil，表示同步成功
+func (s *ActivityService) saveSyncCursor(cursor *models.SyncCursor) error {
+	return s.db.Session(&gorm.Session{FullSaveAssociations: true}).Save(cursor).Error
+}
+
+// 获取用户某一天活动下的某类记录，table为记录所在的表名
+func getDayRecords[T any](db *gorm.DB, table string, userID uint, date time.Time) ([]T, error) {
+	dateStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	dateEnd := dateStart.Add(24 * time.Hour)
+
+	var records []T
+	if err := db.Joins(fmt.Sprintf("JOIN activities ON activities.id = %s.activity_id", table)).
+		Where("activities.user_id = ? AND activities.date >= ? AND activities.date < ? AND activities.deleted_at IS NULL", userID, dateStart, dateEnd).
+		Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// 合并已有记录和新记录，按key去重。key同时负责清空记录的主键和外键，以便重新写入
+func mergeByExternalID[T any](existing, incoming []T, key func(*T) string) []T {
+	seen := make(map[string]bool)
+	merged := make([]T, 0, len(existing)+len(incoming))
+	for _, record := range append(existing, incoming...) {
+		k := key(&record)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		merged = append(merged, record)
+	}
+	return merged
+}
+
+// 按hash合并提交，已有的提交优先；推送事件中的提交缺少时间和行数，获取到完整的提交后替换
+func mergeCommits(existing, incoming []models.Commit) []models.Commit {
+	index := make(map[string]int)
+	merged := make([]models.Commit, 0, len(existing)+len(incoming))
+	for _, commit := range append(existing, incoming...) {
+		commit.ID = 0
+		commit.ActivityID = 0
+		if i, ok := index[commit.Hash]; ok {
+			if merged[i].Partial && !commit.Partial {
+				merged[i] = commit
+			}
+			continue
+		}
+		index[commit.Hash] = len(merged)
+		merged = append(merged, commit)
+	}
+	return merged
+}
+
+// 推送事件中的提交按推送时间保存，完整的提交可能属于另一天，从原来的日期移除
+func (s *ActivityService) dropPartialCommits(userID uint, batch *SyncBatch) error {
+	days := make(map[string]string)
+	var hashes []string
+	for day, commits := range batch.commits {
+		for _, commit := range commits {
+			if !commit.Partial {
+				days[commit.Hash] = day
+				hashes = append(hashes, commit.Hash)
+			}
+		}
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	var rows []struct {
+		Hash string
+		Date time.Time
+	}
+	if err := s.db.Model(&models.Commit{}).
+		Select("commits.hash AS hash, activities.date AS date").
+		Joins("JOIN activities ON activities.id = commits.activity_id").
+		Where("activities.user_id = ? AND activities.deleted_at IS NULL AND commits.partial = ? AND commits.hash IN ?", userID, true, hashes).
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if day := localDay(row.Date); day != days[row.Hash] {
+			batch.dropCommit(day, row.Hash)
+		}
+	}
 	return nil
 }
 
+func hasDataSource(dataSources []models.DataSource, dsType string) bool {
+	for _, ds := range dataSources {
+		if ds.Type == dsType {
+			return true
+		}
+	}
+	return false
+}
+
+// 从已有的dsType数据源中取出id以prefixes之一开头的记录
+func keptRecords(existing []models.DataSource, dsType string, prefixes []string) ([]models.DataSource, error) {
+	var kept []models.DataSource
+	for _, ds := range existing {
+		if ds.Type != dsType {
+			continue
+		}
+
+		var records []json.RawMessage
+		if err := json.Unmarshal([]byte(ds.Data), &records); err != nil {
+			return nil, err
+		}
+		var matched []json.RawMessage
+		for _, record := range records {
+			var key struct {
+				ID string `json:"id"`
+			}
+			json.Unmarshal(record, &key)
+			for _, prefix := range prefixes {
+				if strings.HasPrefix(key.ID, prefix) {
+					matched = append(matched, record)
+					break
+				}
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+
+		data, err := json.Marshal(matched)
+		if err != nil {
+			return nil, err
+		}
+		kept = append(kept, models.DataSource{Type: dsType, Data: string(data)})
+	}
+	return kept, nil
+}
+
+// 合并同类型的事件数据源，Data为带id字段的JSON数组，按id去重，新记录覆盖旧记录。
+// 不在incoming中的类型原样保留，summaryTypes中的汇总类型会由调用方重新生成
+func mergeDataSources(existing, incoming []models.DataSource, summaryTypes map[string]bool) ([]models.DataSource, error) {
+	byType := make(map[string][]json.RawMessage)
+	var types []string
+	add := func(ds models.DataSource) error {
+		var records []json.RawMessage
+		if err := json.Unmarshal([]byte(ds.Data), &records); err != nil {
+			return err
+		}
+		if _, ok := byType[ds.Type]; !ok {
+			types = append(types, ds.Type)
+		}
+		byType[ds.Type] = append(byType[ds.Type], records...)
+		return nil
+	}
+
+	incomingTypes := make(map[string]bool)
+	for _, ds := range incoming {
+		incomingTypes[ds.Type] = true
+	}
+
+	var merged []models.DataSource
+	for _, ds := range existing {
+		if summaryTypes[ds.Type] {
+			continue
+		}
+		if !incomingTypes[ds.Type] {
+			merged = append(merged, models.DataSource{Type: ds.Type, Data: ds.Data})
+			continue
+		}
+		if err := add(ds); err != nil {
+			return nil, err
+		}
+	}
+	for _, ds := range incoming {
+		if err := add(ds); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, dsType := range types {
+		seen := make(map[string]int)
+		var records []json.RawMessage
+		for _, record := range byType[dsType] {
+			var key struct {
+				ID string `json:"id"`
+			}
+			json.Unmarshal(record, &key)
+			// 同id的记录以后出现的（新同步的）为准
+			if key.ID != "" {
+				if index, ok := seen[key.ID]; ok {
+					records[index] = record
+					continue
+				}
+				seen[key.ID] = len(records)
+			}
+			records = append(records, record)
+		}
+
+		data, err := json.Marshal(records)
+		if err != nil {
+			return nil, err
+		}
+		merged = append(merged, models.DataSource{Type: dsType, Data: string(data)})
+	}
+
+	return merged, nil
+}
+
 func (s *ActivityService) GetTodayActivity(userID uint) (*models.Activity, error) {
 	now := time.Now()
 	dateStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
@@ -179,6 +972,10 @@ func (s *ActivityService) GetTodayActivity(userID uint) (*models.Activity, error
 	err := s.db.Where("user_id = ? AND date >= ? AND date < ?", userID, dateStart, dateEnd).
 		Preload("Commits").
 		Preload("DataSources").
+		Preload("PullRequests").
+		Preload("Reviews").
+		Preload("IssueEvents").
+		Preload("ManualEntries").
 		First(&activity).Error
 
 	if err != nil && err != gorm.ErrRecordNotFound {
@@ -189,5 +986,34 @@ func (s *ActivityService) GetTodayActivity(userID uint) (*models.Activity, error
 		return nil, nil
 	}
 
+	if err := s.hideRepositories(userID, &activity); err != nil {
+		return nil, err
+	}
+
 	return &activity, nil
+}
+
+// 记录当天某个数据源提交的概要，便于前端展示来源，Type为数据源名称
+func commitDataSource(source string, commits []models.Commit) (models.DataSource, error) {
+	repoSet := make(map[string]bool)
+	var repos []string
+	for _, commit := range commits {
+		if !repoSet[commit.Repository] {
+			repoSet[commit.Repository] = true
+			repos = append(repos, commit.Repository)
+		}
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"commits":      len(commits),
+		"repositories": repos,
+	})
+	if err != nil {
+		return models.DataSource{}, err
+	}
+
+	return models.DataSource{
+		Type: source,
+		Data: string(data),
+	}, nil
 }
\ No newline at end of file
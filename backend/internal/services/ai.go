@@ -1,19 +1,37 @@
 package services
 
 import (
+	"context"
 	"myvault-backend/pkg/ai"
 )
 
 type AIService struct {
-	client *ai.OpenAIClient
+	provider ai.Provider
 }
 
-func NewAIService(apiKey string) *AIService {
+func NewAIService(provider ai.Provider) *AIService {
 	return &AIService{
-		client: ai.NewOpenAIClient(apiKey),
+		provider: provider,
 	}
 }
 
-func (s *AIService) GenerateSummary(prompt string) (string, error) {
-	return s.client.GenerateSummary(prompt)
-}
\ No newline at end of file
+func (s *AIService) Name() string {
+	return s.provider.Name()
+}
+
+// Identity 标识生成摘要的Provider和模型，计入摘要指纹，更换模型后摘要会重新生成
+func (s *AIService) Identity() string {
+	if model := s.provider.Model(); model != "" {
+		return s.provider.Name() + "/" + model
+	}
+	return s.provider.Name()
+}
+
+// Generative 表示摘要是否由大模型生成，模板实现只是排版统计数据，不算AI生成
+func (s *AIService) Generative() bool {
+	return s.provider.Name() != ai.ProviderTemplate
+}
+
+func (s *AIService) GenerateSummary(ctx context.Context, req *ai.SummaryRequest) (string, error) {
+	return s.provider.GenerateSummary(ctx, req)
+}
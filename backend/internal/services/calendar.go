@@ -0,0 +1,172 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"myvault-backend/internal/models"
+	"myvault-backend/pkg/ical"
+	"myvault-backend/pkg/safehttp"
+	"net/http"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCalendarNotFound   = errors.New("日历不存在")
+	ErrInvalidCalendar    = errors.New("无法解析日历文件")
+	ErrCalendarFetch      = errors.New("无法获取日历")
+	ErrCalendarURLBlocked = errors.New("不允许访问该日历地址")
+)
+
+// ICS文件的大小上限
+const maxCalendarSize = 10 << 20
+
+type CalendarService struct {
+	db         *gorm.DB
+	httpClient *http.Client
+}
+
+func NewCalendarService(db *gorm.DB) *CalendarService {
+	return &CalendarService{
+		db:         db,
+		httpClient: safehttp.NewClient(30 * time.Second),
+	}
+}
+
+func (s *CalendarService) GetUserCalendars(userID uint) ([]models.Calendar, error) {
+	var calendars []models.Calendar
+	if err := s.db.Where("user_id = ?", userID).Order("created_at").Find(&calendars).Error; err != nil {
+		return nil, err
+	}
+
+	return calendars, nil
+}
+
+func (s *CalendarService) HasCalendars(userID uint) bool {
+	var count int64
+	s.db.Model(&models.Calendar{}).Where("user_id = ?", userID).Count(&count)
+	return count > 0
+}
+
+// SubscribeCalendar 订阅ICS地址，保存前先获取一次以验证地址可用，每次同步时重新获取
+func (s *CalendarService) SubscribeCalendar(userID uint, req *models.SubscribeCalendarRequest) (*models.Calendar, error) {
+	url := req.URL
+	if strings.HasPrefix(strings.ToLower(url), "webcal://") {
+		url = "https://" + url[len("webcal://"):]
+	}
+	if err := safehttp.ValidateURL(url); err != nil {
+		return nil, ErrCalendarURLBlocked
+	}
+
+	parsed, err := s.fetch(url)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar := models.Calendar{
+		UserID: userID,
+		Name:   calendarName(req.Name, parsed),
+		URL:    url,
+	}
+	if err := s.db.Create(&calendar).Error; err != nil {
+		return nil, err
+	}
+
+	return &calendar, nil
+}
+
+// UploadCalendar 保存上传的ICS文件，适合无法订阅的日历或离线导入
+func (s *CalendarService) UploadCalendar(userID uint, name string, r io.Reader) (*models.Calendar, error) {
+	content, err := io.ReadAll(io.LimitReader(r, maxCalendarSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxCalendarSize {
+		return nil, fmt.Errorf("%w: 文件超过%dMB", ErrInvalidCalendar, maxCalendarSize>>20)
+	}
+
+	parsed, err := ical.Parse(bytes.NewReader(content), time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCalendar, err)
+	}
+
+	calendar := models.Calendar{
+		UserID:  userID,
+		Name:    calendarName(name, parsed),
+		Content: string(content),
+	}
+	if err := s.db.Create(&calendar).Error; err != nil {
+		return nil, err
+	}
+
+	return &calendar, nil
+}
+
+func (s *CalendarService) DeleteCalendar(userID, calendarID uint) error {
+	result := s.db.Where("id = ? AND user_id = ?", calendarID, userID).Delete(&models.Calendar{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrCalendarNotFound
+	}
+
+	return nil
+}
+
+// Load 解析日历内容，订阅的日历每次重新获取
+func (s *CalendarService) Load(calendar *models.Calendar) (*ical.Calendar, error) {
+	if calendar.URL != "" {
+		return s.fetch(calendar.URL)
+	}
+
+	parsed, err := ical.Parse(strings.NewReader(calendar.Content), time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCalendar, err)
+	}
+	return parsed, nil
+}
+
+// 返回的错误包装ErrCalendarFetch、ErrCalendarURLBlocked或ErrInvalidCalendar，
+// 详细原因只用于日志，不要返回给用户
+func (s *CalendarService) fetch(url string) (*ical.Calendar, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCalendarFetch, err)
+	}
+	req.Header.Set("Accept", "text/calendar")
+
+	resp, err := s.httpClient.Do(req)
+	if safehttp.IsForbidden(err) {
+		return nil, fmt.Errorf("%w: %v", ErrCalendarURLBlocked, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCalendarFetch, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%w: status %d", ErrCalendarFetch, resp.StatusCode)
+	}
+
+	parsed, err := ical.Parse(io.LimitReader(resp.Body, maxCalendarSize), time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCalendar, err)
+	}
+	return parsed, nil
+}
+
+// 未指定名称时使用日历中的X-WR-CALNAME
+func calendarName(name string, parsed *ical.Calendar) string {
+	if name != "" {
+		return name
+	}
+	if parsed.Name != "" {
+		return parsed.Name
+	}
+	return "日历"
+}
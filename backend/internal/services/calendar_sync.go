@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"myvault-backend/internal/models"
+	"myvault-backend/pkg/ical"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 会议数据源的类型，Data为当天的meetingRecord数组
+const calendarSourceType = "calendar"
+
+type meetingRecord struct {
+	ID       string    `json:"id"` // 日历ID:UID@开始时间，重复会议的每次实例各不相同
+	Calendar string    `json:"calendar"`
+	Title    string    `json:"title"`
+	Location string    `json:"location,omitempty"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Duration int       `json:"duration"` // 分钟
+}
+
+// CalendarSource 将用户日历中已开始的会议导入时间轴，会议时长计入当天的总时长
+type CalendarSource struct {
+	calendarService *CalendarService
+}
+
+func NewCalendarSource(calendarService *CalendarService) *CalendarSource {
+	return &CalendarSource{calendarService: calendarService}
+}
+
+func (s *CalendarSource) Name() string {
+	return calendarSourceType
+}
+
+func (s *CalendarSource) Connected(user *models.User) bool {
+	return s.calendarService.HasCalendars(user.ID)
+}
+
+// 从上次同步的当天零点开始展开，窗口内每一天的会议记录整体替换，日历中删除或移到其他日期的会议随之移除。
+// 单个日历获取失败时记为失败并保留该日历已导入的会议；游标不推进，下次同步会重新导入
+func (s *CalendarSource) Fetch(sc *SyncContext) (*SyncBatch, error) {
+	calendars, err := s.calendarService.GetUserCalendars(sc.User.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	since := sc.Since().In(time.Local)
+	from := time.Date(since.Year(), since.Month(), since.Day(), 0, 0, 0, 0, time.Local)
+
+	byDay := make(map[string][]meetingRecord)
+	var days []string
+	batch := NewSyncBatch()
+	for i := range calendars {
+		calendar := &calendars[i]
+		parsed, err := s.calendarService.Load(calendar)
+		if err != nil {
+			sc.Fail(calendar.Name, err)
+			batch.Keep(calendarRecordPrefix(calendar.ID))
+			continue
+		}
+		if len(parsed.UnknownTimezones) > 0 {
+			log.Printf("Calendar %d: skipped events in unknown time zones %s", calendar.ID, strings.Join(parsed.UnknownTimezones, ", "))
+		}
+
+		for _, event := range ical.Expand(parsed.Events, from, sc.To) {
+			// 全天事件通常是假期、出差等，不是会议
+			if event.AllDay || event.Start.Before(from) {
+				continue
+			}
+
+			day := localDay(event.Start)
+			if _, ok := byDay[day]; !ok {
+				days = append(days, day)
+			}
+			byDay[day] = append(byDay[day], convertCalendarEvent(calendar, event))
+		}
+	}
+
+	batch.Cover(from, sc.To)
+	for _, day := range days {
+		meetings := byDay[day]
+		sort.SliceStable(meetings, func(i, j int) bool {
+			return meetings[i].Start.Before(meetings[j].Start)
+		})
+
+		data, err := json.Marshal(meetings)
+		if err != nil {
+			return nil, err
+		}
+		batch.AddDataSource(meetings[0].Start, models.DataSource{Type: calendarSourceType, Data: string(data)})
+	}
+
+	return batch, nil
+}
+
+// 会议记录ID的前缀，用于找出某个日历导入的会议
+func calendarRecordPrefix(calendarID uint) string {
+	return fmt.Sprintf("%d:", calendarID)
+}
+
+func convertCalendarEvent(calendar *models.Calendar, event ical.Event) meetingRecord {
+	title := event.Summary
+	if title == "" {
+		title = "(无标题)"
+	}
+
+	return meetingRecord{
+		ID:       fmt.Sprintf("%s%s@%s", calendarRecordPrefix(calendar.ID), event.UID, event.Start.UTC().Format("20060102T150405Z")),
+		Calendar: calendar.Name,
+		Title:    title,
+		Location: event.Location,
+		Start:    event.Start.In(time.Local),
+		End:      event.End.In(time.Local),
+		Duration: int(event.Duration().Round(time.Minute) / time.Minute),
+	}
+}
+
+// RemoveCalendarMeetings 从所有日期中移除某个日历导入的会议并重新计算统计，删除日历后调用
+func (s *ActivityService) RemoveCalendarMeetings(ctx context.Context, userID, calendarID uint) error {
+	prefix := calendarRecordPrefix(calendarID)
+
+	var dates []time.Time
+	if err := s.db.Model(&models.DataSource{}).
+		Joins("JOIN activities ON activities.id = data_sources.activity_id").
+		Where("activities.user_id = ? AND activities.deleted_at IS NULL AND data_sources.type = ? AND data_sources.data LIKE ?",
+			userID, calendarSourceType, `%"id":"`+prefix+`%`).
+		Distinct().
+		Pluck("activities.date", &dates).Error; err != nil {
+		return err
+	}
+
+	for _, date := range dates {
+		_, err := s.rewriteDay(ctx, userID, date, func(data *ActivityData) error {
+			kept := data.DataSources[:0]
+			for _, ds := range data.DataSources {
+				if ds.Type != calendarSourceType {
+					kept = append(kept, ds)
+					continue
+				}
+
+				var meetings []meetingRecord
+				if err := json.Unmarshal([]byte(ds.Data), &meetings); err != nil {
+					return err
+				}
+				remaining := meetings[:0]
+				for _, meeting := range meetings {
+					if !strings.HasPrefix(meeting.ID, prefix) {
+						remaining = append(remaining, meeting)
+					}
+				}
+				if len(remaining) == 0 {
+					continue
+				}
+
+				encoded, err := json.Marshal(remaining)
+				if err != nil {
+					return err
+				}
+				ds.Data = string(encoded)
+				kept = append(kept, ds)
+			}
+			data.DataSources = kept
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 当天的会议，按开始时间排序
+func (d *ActivityData) meetings() []meetingRecord {
+	var meetings []meetingRecord
+	for _, ds := range d.DataSources {
+		if ds.Type != calendarSourceType {
+			continue
+		}
+		var records []meetingRecord
+		if err := json.Unmarshal([]byte(ds.Data), &records); err != nil {
+			continue
+		}
+		meetings = append(meetings, records...)
+	}
+
+	sort.SliceStable(meetings, func(i, j int) bool {
+		return meetings[i].Start.Before(meetings[j].Start)
+	})
+	return meetings
+}
+
+// 会议占用的总时长，重叠的会议只计算一次
+func meetingTime(meetings []meetingRecord) time.Duration {
+	var total time.Duration
+	var end time.Time
+	for _, meeting := range meetings {
+		start := meeting.Start
+		if start.Before(end) {
+			start = end
+		}
+		if meeting.End.After(start) {
+			total += meeting.End.Sub(start)
+			end = meeting.End
+		}
+	}
+	return total
+}
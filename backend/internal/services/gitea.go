@@ -0,0 +1,58 @@
+package services
+
+import (
+	"errors"
+	"myvault-backend/pkg/gitea"
+	"time"
+)
+
+var ErrGiteaURLRequired = errors.New("未配置Gitea实例地址")
+
+type GiteaService struct {
+	// 用户未指定实例地址时使用配置的默认地址（可以为空），用户填写的地址只允许访问公网
+	instances instanceResolver
+}
+
+func NewGiteaService(defaultBaseURL string) *GiteaService {
+	return &GiteaService{instances: newInstanceResolver(defaultBaseURL)}
+}
+
+func (s *GiteaService) client(baseURL string) (*gitea.Client, error) {
+	baseURL, httpClient, err := s.instances.resolve(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if baseURL == "" {
+		return nil, ErrGiteaURLRequired
+	}
+
+	client := gitea.NewClient(baseURL)
+	if httpClient != nil {
+		client.SetHTTPClient(httpClient)
+	}
+	return client, nil
+}
+
+func (s *GiteaService) GetUser(baseURL, accessToken string) (*gitea.User, error) {
+	client, err := s.client(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetUser(accessToken)
+}
+
+func (s *GiteaService) GetUserRepositories(baseURL, accessToken string) ([]gitea.Repository, error) {
+	client, err := s.client(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetUserRepositories(accessToken)
+}
+
+func (s *GiteaService) GetRepositoryCommits(baseURL, accessToken, repo string, since time.Time) ([]gitea.Commit, error) {
+	client, err := s.client(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetRepositoryCommits(accessToken, repo, since)
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"errors"
+	"myvault-backend/internal/models"
+	"myvault-backend/pkg/gitea"
+	"strings"
+)
+
+type GiteaSource struct {
+	giteaService      *GiteaService
+	repositoryService *RepositoryService
+}
+
+func NewGiteaSource(giteaService *GiteaService, repositoryService *RepositoryService) *GiteaSource {
+	return &GiteaSource{
+		giteaService:      giteaService,
+		repositoryService: repositoryService,
+	}
+}
+
+func (s *GiteaSource) Name() string {
+	return "gitea"
+}
+
+func (s *GiteaSource) Connected(user *models.User) bool {
+	return user.GiteaToken != "" && user.GiteaUsername != ""
+}
+
+// 逐仓库获取Gitea提交，游标和中止语义与GitHub一致
+func (s *GiteaSource) Fetch(sc *SyncContext) (*SyncBatch, error) {
+	user := sc.User
+
+	allRepos, err := s.giteaService.GetUserRepositories(user.GiteaBaseURL, user.GiteaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	discovered := make([]models.Repository, 0, len(allRepos))
+	empty := make(map[string]bool)
+	for _, repo := range allRepos {
+		discovered = append(discovered, convertGiteaRepository(repo))
+		empty[repo.FullName] = repo.Empty
+	}
+	repos, _, err := discoverRepositories(s.repositoryService, user, s.Name(), discovered)
+	if err != nil {
+		return nil, err
+	}
+
+	sc.AddRepositories(len(repos))
+
+	repoCursors := repositoryCursors(sc.Cursor)
+
+	batch := NewSyncBatch()
+	var abortErr error
+	for _, repo := range repos {
+		if empty[repo.FullName] {
+			sc.RepositoryDone(repo.FullName, nil)
+			continue
+		}
+
+		repoCursor := repoCursors[repo.FullName]
+
+		giteaCommits, err := s.giteaService.GetRepositoryCommits(user.GiteaBaseURL, user.GiteaToken, repo.FullName, sc.RepositorySince(repoCursor))
+		sc.RepositoryDone(repo.FullName, err)
+		if err != nil {
+			// 令牌失效和限流会影响所有仓库，中止同步；403等只记录该仓库的失败
+			if errors.Is(err, gitea.ErrRateLimited) || errors.Is(err, gitea.ErrUnauthorized) {
+				abortErr = err
+				break
+			}
+			continue
+		}
+
+		for _, gc := range giteaCommits {
+			if !sc.Force && repoCursor != nil && gc.SHA == repoCursor.LastSHA {
+				continue
+			}
+			if !isGiteaAuthor(user, gc) {
+				continue
+			}
+			batch.AddCommit(convertGiteaCommit(gc))
+		}
+
+		// 游标记录仓库的最新提交，不论作者是谁，避免下次重复获取
+		if len(giteaCommits) > 0 {
+			if repoCursor == nil {
+				repoCursor = &models.RepositoryCursor{Repository: repo.FullName}
+				repoCursors[repo.FullName] = repoCursor
+			}
+			repoCursor.LastSHA = giteaCommits[0].SHA
+			repoCursor.LastCommitAt = giteaCommits[0].Commit.Author.Date
+		}
+	}
+
+	saveRepositoryCursors(sc.Cursor, repoCursors)
+
+	return batch, abortErr
+}
+
+// Gitea的提交接口不能按作者过滤，按关联账号或提交邮箱判断
+func isGiteaAuthor(user *models.User, gc gitea.Commit) bool {
+	if gc.Author != nil && strings.EqualFold(gc.Author.Login, user.GiteaUsername) {
+		return true
+	}
+	return user.GiteaEmail != "" && strings.EqualFold(gc.Commit.Author.Email, user.GiteaEmail)
+}
+
+func convertGiteaCommit(gc gitea.Commit) models.Commit {
+	return models.Commit{
+		Hash:       gc.SHA,
+		Message:    gc.Commit.Message,
+		Repository: gc.Repository,
+		Author:     gc.Commit.Author.Name,
+		Time:       gc.Commit.Author.Date,
+		Files:      len(gc.Files),
+		Additions:  gc.Stats.Additions,
+		Deletions:  gc.Stats.Deletions,
+	}
+}
+
+func convertGiteaRepository(repo gitea.Repository) models.Repository {
+	return models.Repository{
+		Name:        repo.Name,
+		FullName:    repo.FullName,
+		Description: repo.Description,
+		Language:    repo.Language,
+		Private:     repo.Private,
+		Fork:        repo.Fork,
+		Archived:    repo.Archived,
+	}
+}
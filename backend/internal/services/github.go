@@ -1,16 +1,28 @@
 package services
 
 import (
+	"log"
 	"myvault-backend/pkg/github"
+	"time"
+
+	"github.com/redis/go-redis/v9"
 )
 
 type GithubService struct {
 	client *github.Client
+	// 获取提交详情的并发数，0表示逐仓库同步时不获取
+	detailConcurrency int
 }
 
-func NewGithubService(clientID, clientSecret string) *GithubService {
+func NewGithubService(clientID, clientSecret string, detailConcurrency int, redis *redis.Client) *GithubService {
+	client := github.NewClient(clientID, clientSecret)
+	if redis != nil {
+		client.SetCache(github.NewRedisCache(redis))
+	}
+
 	return &GithubService{
-		client: github.NewClient(clientID, clientSecret),
+		client:            client,
+		detailConcurrency: detailConcurrency,
 	}
 }
 
@@ -22,7 +34,46 @@ func (s *GithubService) GetUser(accessToken string) (*github.User, error) {
 	return s.client.GetUser(accessToken)
 }
 
-func (s *GithubService) GetUserCommits(accessToken, username string, since string) ([]github.Commit, error) {
-	// 这里暂时返回空数组，实际实现需要解析since参数
-	return []github.Commit{}, nil
+func (s *GithubService) GetUserCommits(accessToken, username string, since time.Time) ([]github.Commit, error) {
+	return s.client.GetUserCommits(accessToken, username, since)
+}
+
+func (s *GithubService) GetUserRepositories(accessToken string) ([]github.Repository, error) {
+	return s.client.GetUserRepositories(accessToken)
+}
+
+// allBranches为false时只获取默认分支的提交
+func (s *GithubService) GetRepositoryCommits(accessToken, repoFullName, author string, since time.Time, allBranches bool) ([]github.Commit, error) {
+	var commits []github.Commit
+	var err error
+	if allBranches {
+		commits, err = s.client.GetAllBranchCommits(accessToken, repoFullName, author, since)
+	} else {
+		commits, err = s.client.GetRepositoryCommits(accessToken, repoFullName, author, since)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if s.detailConcurrency > 0 && len(commits) > 0 {
+		// 详情获取失败时保留列表数据，行数统计为0
+		if err := s.client.FetchCommitDetails(accessToken, commits, s.detailConcurrency); err != nil {
+			log.Printf("Failed to fetch commit details for %s: %v", repoFullName, err)
+		}
+	}
+
+	return commits, nil
+}
+
+// 按SHA获取完整的提交，未开启详情获取时也会逐个请求
+func (s *GithubService) GetCommitDetails(accessToken string, commits []github.Commit) error {
+	return s.client.FetchCommitDetails(accessToken, commits, s.detailConcurrency)
+}
+
+func (s *GithubService) GetUserEvents(accessToken, username string, since time.Time) ([]github.Event, bool, error) {
+	return s.client.GetUserEvents(accessToken, username, since)
+}
+
+func (s *GithubService) SearchUserIssues(accessToken, username string, since time.Time) ([]github.SearchIssue, error) {
+	return s.client.SearchUserIssues(accessToken, username, since)
 }
\ No newline at end of file
@@ -0,0 +1,454 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"myvault-backend/internal/models"
+	"myvault-backend/pkg/github"
+	"strings"
+	"time"
+)
+
+const (
+	// 距离上次同步不超过该时长时使用事件流同步
+	eventsSyncWindow = 7 * 24 * time.Hour
+	// 事件流起点向前多取一段时间，避免边界上的事件丢失
+	eventsSyncOverlap = 5 * time.Minute
+)
+
+type GithubSource struct {
+	githubService     *GithubService
+	repositoryService *RepositoryService
+}
+
+func NewGithubSource(githubService *GithubService, repositoryService *RepositoryService) *GithubSource {
+	return &GithubSource{
+		githubService:     githubService,
+		repositoryService: repositoryService,
+	}
+}
+
+func (s *GithubSource) Name() string {
+	return "github"
+}
+
+func (s *GithubSource) Connected(user *models.User) bool {
+	return user.AccessToken != "" && user.GithubUsername != ""
+}
+
+// 距离上次同步不久时优先使用事件流，事件流不完整时回退到逐仓库获取
+func (s *GithubSource) Fetch(sc *SyncContext) (*SyncBatch, error) {
+	lastSyncAt := sc.Cursor.LastSyncAt
+	if !sc.Force && lastSyncAt != nil && sc.To.Sub(*lastSyncAt) <= eventsSyncWindow {
+		batch, complete, err := s.fetchEvents(sc, lastSyncAt.Add(-eventsSyncOverlap))
+		if err != nil {
+			log.Printf("Failed to sync events for user %d, falling back to repositories: %v", sc.User.ID, err)
+		}
+		if err == nil && complete {
+			return batch, nil
+		}
+	}
+
+	return s.fetchCommits(sc)
+}
+
+func prExternalID(repo string, number int, action string) string {
+	return fmt.Sprintf("%s#%d:%s", repo, number, action)
+}
+
+// 逐仓库获取提交
+func (s *GithubSource) fetchCommits(sc *SyncContext) (*SyncBatch, error) {
+	user := sc.User
+
+	allRepos, err := s.githubService.GetUserRepositories(user.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	discovered := make([]models.Repository, 0, len(allRepos))
+	for _, repo := range allRepos {
+		discovered = append(discovered, convertGithubRepository(repo))
+	}
+	repos, statuses, err := discoverRepositories(s.repositoryService, user, s.Name(), discovered)
+	if err != nil {
+		return nil, err
+	}
+
+	sc.AddRepositories(len(repos))
+
+	repoCursors := repositoryCursors(sc.Cursor)
+
+	batch := NewSyncBatch()
+	var abortErr error
+	for _, repo := range repos {
+		repoCursor := repoCursors[repo.FullName]
+
+		githubCommits, err := s.githubService.GetRepositoryCommits(user.AccessToken, repo.FullName, user.GithubUsername, sc.RepositorySince(repoCursor), user.AllBranches)
+		sc.RepositoryDone(repo.FullName, err)
+		if err != nil {
+			// 限流或令牌失效时后续仓库同样会失败，先保存已获取的数据
+			if errors.Is(err, github.ErrRateLimited) || errors.Is(err, github.ErrUnauthorized) {
+				abortErr = err
+				break
+			}
+			continue
+		}
+
+		for _, gc := range githubCommits {
+			// since包含边界，跳过上次已经看到的提交
+			if !sc.Force && repoCursor != nil && gc.SHA == repoCursor.LastSHA {
+				continue
+			}
+			batch.AddCommit(convertGithubCommit(gc))
+		}
+
+		if len(githubCommits) > 0 {
+			if repoCursor == nil {
+				repoCursor = &models.RepositoryCursor{Repository: repo.FullName}
+				repoCursors[repo.FullName] = repoCursor
+			}
+			repoCursor.LastSHA = githubCommits[0].SHA
+			repoCursor.LastCommitAt = githubCommits[0].Commit.Author.Date
+		}
+	}
+
+	saveRepositoryCursors(sc.Cursor, repoCursors)
+
+	// 逐仓库获取不到PR和issue，通过搜索接口补充
+	if abortErr == nil {
+		if err := s.fetchIssues(user, sc.Since(), statuses, batch); err != nil {
+			sc.Fail("search/issues", err)
+		}
+	}
+
+	return batch, abortErr
+}
+
+func (s *GithubSource) fetchIssues(user *models.User, since time.Time, statuses map[string]string, batch *SyncBatch) error {
+	items, err := s.githubService.SearchUserIssues(user.AccessToken, user.GithubUsername, since)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		repo := item.Repository()
+		if statuses[repo] == models.RepositoryIgnored {
+			continue
+		}
+
+		if item.IsPullRequest() {
+			batch.AddPullRequest(models.PullRequest{
+				ExternalID: prExternalID(repo, item.Number, "opened"),
+				Repository: repo,
+				Number:     item.Number,
+				Title:      item.Title,
+				Action:     "opened",
+				State:      item.State,
+				URL:        item.HTMLURL,
+				Time:       item.CreatedAt,
+			})
+		} else {
+			batch.AddIssueEvent(models.IssueEvent{
+				ExternalID: prExternalID(repo, item.Number, "opened"),
+				Repository: repo,
+				Number:     item.Number,
+				Title:      item.Title,
+				Action:     "opened",
+				State:      item.State,
+				URL:        item.HTMLURL,
+				Time:       item.CreatedAt,
+			})
+		}
+	}
+
+	return nil
+}
+
+// 事件流中单条事件的精简记录，保存在对应类型DataSource的Data数组中
+type githubEventRecord struct {
+	ID         string    `json:"id"`
+	Repository string    `json:"repository"`
+	Action     string    `json:"action,omitempty"`
+	Number     int       `json:"number,omitempty"`
+	Title      string    `json:"title,omitempty"`
+	State      string    `json:"state,omitempty"`
+	URL        string    `json:"url,omitempty"`
+	Ref        string    `json:"ref,omitempty"`
+	Commits    int       `json:"commits,omitempty"`
+	Time       time.Time `json:"time"`
+}
+
+var githubEventSourceTypes = map[string]string{
+	github.PushEventType:              "github_push",
+	github.PullRequestEventType:       "github_pull_request",
+	github.PullRequestReviewEventType: "github_review",
+	github.IssuesEventType:            "github_issue",
+}
+
+func isGithubEventSourceType(dsType string) bool {
+	for _, sourceType := range githubEventSourceTypes {
+		if sourceType == dsType {
+			return true
+		}
+	}
+	return false
+}
+
+// 移除隐藏仓库的事件记录，没有剩余记录时ok为false
+func filterGithubEventRecords(data string, hidden map[string]bool) (string, bool, error) {
+	var records []githubEventRecord
+	if err := json.Unmarshal([]byte(data), &records); err != nil {
+		return "", false, err
+	}
+
+	visible := records[:0]
+	for _, record := range records {
+		if !hidden[record.Repository] {
+			visible = append(visible, record)
+		}
+	}
+	if len(visible) == 0 {
+		return "", false, nil
+	}
+	if len(visible) == len(records) {
+		return data, true, nil
+	}
+
+	encoded, err := json.Marshal(visible)
+	if err != nil {
+		return "", false, err
+	}
+	return string(encoded), true, nil
+}
+
+// 通过事件流获取since之后的活动，complete为false时表示事件流没有覆盖到since
+func (s *GithubSource) fetchEvents(sc *SyncContext, since time.Time) (*SyncBatch, bool, error) {
+	user := sc.User
+	events, complete, err := s.githubService.GetUserEvents(user.AccessToken, user.GithubUsername, since)
+	if err != nil || !complete {
+		return nil, complete, err
+	}
+
+	// 事件流不包含仓库元数据，使用已保存的仓库信息过滤
+	repos, err := s.repositoryService.RepositoryMap(user.ID, s.Name())
+	if err != nil {
+		return nil, false, err
+	}
+
+	records := make(map[string]map[string][]githubEventRecord)
+	var pushed []models.Commit
+	batch := NewSyncBatch()
+	for _, event := range events {
+		sourceType, ok := githubEventSourceTypes[event.Type]
+		if !ok {
+			continue
+		}
+		if repo, ok := repos[event.Repo.Name]; ok && !shouldSyncRepository(user, repo) {
+			continue
+		}
+
+		record, commits, err := convertGithubEvent(user, event)
+		if err != nil {
+			return nil, false, err
+		}
+
+		pushed = append(pushed, commits...)
+		addGithubEventRecord(batch, event.Type, record)
+
+		day := localDay(event.CreatedAt)
+		if records[day] == nil {
+			records[day] = make(map[string][]githubEventRecord)
+		}
+		records[day][sourceType] = append(records[day][sourceType], record)
+	}
+
+	for _, byType := range records {
+		for sourceType, dayRecords := range byType {
+			data, err := json.Marshal(dayRecords)
+			if err != nil {
+				return nil, false, err
+			}
+			batch.AddDataSource(dayRecords[0].Time, models.DataSource{Type: sourceType, Data: string(data)})
+		}
+	}
+
+	for _, commit := range s.backfillCommits(sc, pushed) {
+		batch.AddCommit(commit)
+	}
+
+	return batch, true, nil
+}
+
+// 推送事件中的提交没有提交时间和行数，按hash获取完整的提交替换。
+// 获取失败的提交保留推送事件中的信息，记录失败后不推进同步游标，下次同步会重新获取这些推送
+func (s *GithubSource) backfillCommits(sc *SyncContext, commits []models.Commit) []models.Commit {
+	if len(commits) == 0 {
+		return commits
+	}
+
+	details := make([]github.Commit, len(commits))
+	for i, commit := range commits {
+		details[i] = github.Commit{SHA: commit.Hash, Repository: commit.Repository}
+	}
+	if err := s.githubService.GetCommitDetails(sc.User.AccessToken, details); err != nil {
+		sc.Fail("commits", err)
+	}
+
+	for i, detail := range details {
+		if !detail.Commit.Author.Date.IsZero() {
+			commits[i] = convertGithubCommit(detail)
+		}
+	}
+	return commits
+}
+
+// 将PR、review和issue事件保存为独立的记录
+func addGithubEventRecord(batch *SyncBatch, eventType string, record githubEventRecord) {
+	switch eventType {
+	case github.PullRequestEventType:
+		batch.AddPullRequest(models.PullRequest{
+			ExternalID: prExternalID(record.Repository, record.Number, record.Action),
+			Repository: record.Repository,
+			Number:     record.Number,
+			Title:      record.Title,
+			Action:     record.Action,
+			State:      record.State,
+			URL:        record.URL,
+			Time:       record.Time,
+		})
+	case github.PullRequestReviewEventType:
+		batch.AddReview(models.Review{
+			ExternalID: "review:" + record.ID,
+			Repository: record.Repository,
+			PullNumber: record.Number,
+			PullTitle:  record.Title,
+			State:      record.State,
+			URL:        record.URL,
+			Time:       record.Time,
+		})
+	case github.IssuesEventType:
+		batch.AddIssueEvent(models.IssueEvent{
+			ExternalID: prExternalID(record.Repository, record.Number, record.Action),
+			Repository: record.Repository,
+			Number:     record.Number,
+			Title:      record.Title,
+			Action:     record.Action,
+			State:      record.State,
+			URL:        record.URL,
+			Time:       record.Time,
+		})
+	}
+}
+
+func convertGithubEvent(user *models.User, event github.Event) (githubEventRecord, []models.Commit, error) {
+	record := githubEventRecord{
+		ID:         event.ID,
+		Repository: event.Repo.Name,
+		Time:       event.CreatedAt,
+	}
+
+	var commits []models.Commit
+	switch event.Type {
+	case github.PushEventType:
+		payload, err := event.PushPayload()
+		if err != nil {
+			return record, nil, err
+		}
+		record.Ref = payload.Ref
+		// 推送事件不包含提交时间和行数，先使用推送时间，由backfillCommits按hash补全。
+		// 一次推送可能包含其他人的提交（如合并了同事的分支），只保留用户自己的
+		for _, pc := range payload.Commits {
+			if !pc.Distinct || !isGithubUserCommit(user, pc.Author.Name, pc.Author.Email) {
+				continue
+			}
+			commits = append(commits, models.Commit{
+				Hash:       pc.SHA,
+				Message:    pc.Message,
+				Repository: event.Repo.Name,
+				Author:     pc.Author.Name,
+				Time:       event.CreatedAt,
+				Partial:    true,
+			})
+		}
+		record.Commits = len(commits)
+	case github.PullRequestEventType:
+		payload, err := event.PullRequestPayload()
+		if err != nil {
+			return record, nil, err
+		}
+		record.Action = payload.Action
+		if payload.Action == "closed" && payload.PullRequest.Merged {
+			record.Action = "merged"
+		}
+		record.Number = payload.PullRequest.Number
+		record.Title = payload.PullRequest.Title
+		record.State = payload.PullRequest.State
+		record.URL = payload.PullRequest.HTMLURL
+	case github.PullRequestReviewEventType:
+		payload, err := event.ReviewPayload()
+		if err != nil {
+			return record, nil, err
+		}
+		record.Action = payload.Action
+		record.Number = payload.PullRequest.Number
+		record.Title = payload.PullRequest.Title
+		record.ID = fmt.Sprintf("%d", payload.Review.ID)
+		record.State = payload.Review.State
+		record.URL = payload.Review.HTMLURL
+	case github.IssuesEventType:
+		payload, err := event.IssuesPayload()
+		if err != nil {
+			return record, nil, err
+		}
+		record.Action = payload.Action
+		record.Number = payload.Issue.Number
+		record.Title = payload.Issue.Title
+		record.State = payload.Issue.State
+		record.URL = payload.Issue.HTMLURL
+	}
+
+	return record, commits, nil
+}
+
+// 推送事件中的提交作者只有git配置的姓名和邮箱，与账号邮箱、GitHub的noreply邮箱
+// （[id+]login@users.noreply.github.com）或登录名相同时认为是用户自己的提交
+func isGithubUserCommit(user *models.User, name, email string) bool {
+	login := user.GithubUsername
+	if email != "" && strings.EqualFold(email, user.Email) {
+		return true
+	}
+	if local, ok := strings.CutSuffix(strings.ToLower(email), "@users.noreply.github.com"); ok {
+		if _, after, found := strings.Cut(local, "+"); found {
+			local = after
+		}
+		return login != "" && strings.EqualFold(local, login)
+	}
+	return login != "" && strings.EqualFold(name, login)
+}
+
+func convertGithubCommit(gc github.Commit) models.Commit {
+	return models.Commit{
+		Hash:       gc.SHA,
+		Message:    gc.Commit.Message,
+		Repository: gc.Repository,
+		Author:     gc.Commit.Author.Name,
+		Time:       gc.Commit.Author.Date,
+		Files:      len(gc.Files),
+		Additions:  gc.Stats.Additions,
+		Deletions:  gc.Stats.Deletions,
+	}
+}
+
+func convertGithubRepository(repo github.Repository) models.Repository {
+	return models.Repository{
+		Name:        repo.Name,
+		FullName:    repo.FullName,
+		Description: repo.Description,
+		Language:    repo.Language,
+		Private:     repo.Private,
+		Fork:        repo.Fork,
+		Archived:    repo.Archived,
+	}
+}
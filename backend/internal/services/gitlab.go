@@ -0,0 +1,52 @@
+package services
+
+import (
+	"myvault-backend/pkg/gitlab"
+	"time"
+)
+
+type GitlabService struct {
+	// 用户未指定实例地址时使用配置的默认地址，用户填写的地址只允许访问公网
+	instances instanceResolver
+}
+
+func NewGitlabService(defaultBaseURL string) *GitlabService {
+	return &GitlabService{instances: newInstanceResolver(defaultBaseURL)}
+}
+
+func (s *GitlabService) client(baseURL string) (*gitlab.Client, error) {
+	baseURL, httpClient, err := s.instances.resolve(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	client := gitlab.NewClient(baseURL)
+	if httpClient != nil {
+		client.SetHTTPClient(httpClient)
+	}
+	return client, nil
+}
+
+func (s *GitlabService) GetUser(baseURL, accessToken string) (*gitlab.User, error) {
+	client, err := s.client(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetUser(accessToken)
+}
+
+func (s *GitlabService) GetProjects(baseURL, accessToken string) ([]gitlab.Project, error) {
+	client, err := s.client(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetProjects(accessToken)
+}
+
+func (s *GitlabService) GetProjectCommits(baseURL, accessToken string, project gitlab.Project, author string, since time.Time) ([]gitlab.Commit, error) {
+	client, err := s.client(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetProjectCommits(accessToken, project, author, since)
+}
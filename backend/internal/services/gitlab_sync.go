@@ -0,0 +1,118 @@
+package services
+
+import (
+	"errors"
+	"myvault-backend/internal/models"
+	"myvault-backend/pkg/gitlab"
+)
+
+type GitlabSource struct {
+	gitlabService     *GitlabService
+	repositoryService *RepositoryService
+}
+
+func NewGitlabSource(gitlabService *GitlabService, repositoryService *RepositoryService) *GitlabSource {
+	return &GitlabSource{
+		gitlabService:     gitlabService,
+		repositoryService: repositoryService,
+	}
+}
+
+func (s *GitlabSource) Name() string {
+	return "gitlab"
+}
+
+func (s *GitlabSource) Connected(user *models.User) bool {
+	return user.GitlabToken != ""
+}
+
+// 逐项目获取GitLab提交，游标和中止语义与GitHub一致
+func (s *GitlabSource) Fetch(sc *SyncContext) (*SyncBatch, error) {
+	user := sc.User
+
+	projects, err := s.gitlabService.GetProjects(user.GitlabBaseURL, user.GitlabToken)
+	if err != nil {
+		return nil, err
+	}
+
+	discovered := make([]models.Repository, 0, len(projects))
+	byName := make(map[string]gitlab.Project, len(projects))
+	for _, project := range projects {
+		discovered = append(discovered, convertGitlabProject(project))
+		byName[project.PathWithNamespace] = project
+	}
+	repos, _, err := discoverRepositories(s.repositoryService, user, s.Name(), discovered)
+	if err != nil {
+		return nil, err
+	}
+
+	sc.AddRepositories(len(repos))
+
+	repoCursors := repositoryCursors(sc.Cursor)
+
+	// GitLab按提交者名字或邮箱过滤作者
+	author := user.GitlabEmail
+	if author == "" {
+		author = user.GitlabUsername
+	}
+
+	batch := NewSyncBatch()
+	var abortErr error
+	for _, repo := range repos {
+		repoCursor := repoCursors[repo.FullName]
+
+		gitlabCommits, err := s.gitlabService.GetProjectCommits(user.GitlabBaseURL, user.GitlabToken, byName[repo.FullName], author, sc.RepositorySince(repoCursor))
+		sc.RepositoryDone(repo.FullName, err)
+		if err != nil {
+			// 令牌失效和限流会影响所有项目，中止同步；403等只记录该项目的失败
+			if errors.Is(err, gitlab.ErrRateLimited) || errors.Is(err, gitlab.ErrUnauthorized) {
+				abortErr = err
+				break
+			}
+			continue
+		}
+
+		for _, gc := range gitlabCommits {
+			if !sc.Force && repoCursor != nil && gc.ID == repoCursor.LastSHA {
+				continue
+			}
+			batch.AddCommit(convertGitlabCommit(gc))
+		}
+
+		if len(gitlabCommits) > 0 {
+			if repoCursor == nil {
+				repoCursor = &models.RepositoryCursor{Repository: repo.FullName}
+				repoCursors[repo.FullName] = repoCursor
+			}
+			repoCursor.LastSHA = gitlabCommits[0].ID
+			repoCursor.LastCommitAt = gitlabCommits[0].AuthoredDate
+		}
+	}
+
+	saveRepositoryCursors(sc.Cursor, repoCursors)
+
+	return batch, abortErr
+}
+
+func convertGitlabCommit(gc gitlab.Commit) models.Commit {
+	return models.Commit{
+		Hash:       gc.ID,
+		Message:    gc.Message,
+		Repository: gc.Project,
+		Author:     gc.AuthorName,
+		Time:       gc.AuthoredDate,
+		Additions:  gc.Stats.Additions,
+		Deletions:  gc.Stats.Deletions,
+	}
+}
+
+func convertGitlabProject(project gitlab.Project) models.Repository {
+	return models.Repository{
+		Name:        project.Name,
+		FullName:    project.PathWithNamespace,
+		Description: project.Description,
+		Private:     project.Visibility != "public",
+		Fork:        project.Fork(),
+		Archived:    project.Archived,
+	}
+}
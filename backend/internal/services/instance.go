@@ -0,0 +1,35 @@
+package services
+
+import (
+	"errors"
+	"myvault-backend/pkg/safehttp"
+	"net/http"
+	"time"
+)
+
+var ErrBaseURLBlocked = errors.New("不允许访问该实例地址")
+
+// 用户填写的实例地址只能访问公网，避免服务器被用来访问内网；
+// 服务端配置的默认地址不受限制，可以是内网部署的实例
+type instanceResolver struct {
+	defaultBaseURL string
+	safeClient     *http.Client
+}
+
+func newInstanceResolver(defaultBaseURL string) instanceResolver {
+	return instanceResolver{
+		defaultBaseURL: defaultBaseURL,
+		safeClient:     safehttp.NewClient(30 * time.Second),
+	}
+}
+
+// 返回实际使用的地址，以及访问该地址时需要使用的客户端，使用默认地址时客户端为nil
+func (r instanceResolver) resolve(baseURL string) (string, *http.Client, error) {
+	if baseURL == "" || baseURL == r.defaultBaseURL {
+		return r.defaultBaseURL, nil, nil
+	}
+	if err := safehttp.ValidateURL(baseURL); err != nil {
+		return "", nil, ErrBaseURLBlocked
+	}
+	return baseURL, r.safeClient, nil
+}
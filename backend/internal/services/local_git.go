@@ -0,0 +1,143 @@
+package services
+
+import (
+	"errors"
+	"myvault-backend/internal/models"
+	"myvault-backend/pkg/gitlocal"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var ErrPathNotAllowed = errors.New("仓库路径不在允许扫描的目录中")
+
+// LocalGitSource 扫描服务器上的本地git仓库，每个用户只允许扫描各根目录下以自己用户ID命名的子目录
+type LocalGitSource struct {
+	roots             []string
+	repositoryService *RepositoryService
+}
+
+func NewLocalGitSource(roots []string, repositoryService *RepositoryService) *LocalGitSource {
+	cleaned := make([]string, 0, len(roots))
+	for _, root := range roots {
+		cleaned = append(cleaned, filepath.Clean(root))
+	}
+
+	return &LocalGitSource{
+		roots:             cleaned,
+		repositoryService: repositoryService,
+	}
+}
+
+func (s *LocalGitSource) Name() string {
+	return "local"
+}
+
+func (s *LocalGitSource) Connected(user *models.User) bool {
+	return len(s.roots) > 0 && len(user.LocalRepoPaths) > 0
+}
+
+func (s *LocalGitSource) Fetch(sc *SyncContext) (*SyncBatch, error) {
+	user := sc.User
+
+	var discovered []models.Repository
+	for _, path := range user.LocalRepoPaths {
+		path, err := s.resolve(user.ID, path)
+		if err != nil {
+			sc.Fail(path, err)
+			continue
+		}
+		discovered = append(discovered, models.Repository{
+			Name:     filepath.Base(path),
+			FullName: path,
+			Private:  true,
+		})
+	}
+	repos, _, err := discoverRepositories(s.repositoryService, user, s.Name(), discovered)
+	if err != nil {
+		return nil, err
+	}
+
+	sc.AddRepositories(len(repos))
+
+	repoCursors := repositoryCursors(sc.Cursor)
+
+	author := user.LocalGitEmail
+	if author == "" {
+		author = user.Email
+	}
+
+	batch := NewSyncBatch()
+	for _, repo := range repos {
+		repoCursor := repoCursors[repo.FullName]
+
+		localCommits, err := gitlocal.Log(repo.FullName, gitlocal.LogOptions{
+			AuthorEmail: author,
+			Since:       sc.RepositorySince(repoCursor),
+			Until:       sc.To,
+			AllBranches: user.AllBranches,
+		})
+		sc.RepositoryDone(repo.FullName, err)
+		if err != nil {
+			continue
+		}
+
+		for _, lc := range localCommits {
+			if !sc.Force && repoCursor != nil && lc.SHA == repoCursor.LastSHA {
+				continue
+			}
+			batch.AddCommit(convertLocalCommit(repo.FullName, lc))
+		}
+
+		if len(localCommits) > 0 {
+			if repoCursor == nil {
+				repoCursor = &models.RepositoryCursor{Repository: repo.FullName}
+				repoCursors[repo.FullName] = repoCursor
+			}
+			repoCursor.LastSHA = localCommits[0].SHA
+			repoCursor.LastCommitAt = localCommits[0].Date
+		}
+	}
+
+	saveRepositoryCursors(sc.Cursor, repoCursors)
+
+	return batch, nil
+}
+
+// 解析路径中的符号链接，解析后的路径必须位于某个根目录下的<用户ID>目录之中，
+// 避免通过符号链接或其他用户的目录读取任意仓库。返回解析后的路径
+func (s *LocalGitSource) resolve(userID uint, path string) (string, error) {
+	path = filepath.Clean(path)
+	if !filepath.IsAbs(path) {
+		return path, ErrPathNotAllowed
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return path, err
+	}
+
+	for _, root := range s.roots {
+		userRoot, err := filepath.EvalSymlinks(filepath.Join(root, strconv.FormatUint(uint64(userID), 10)))
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(userRoot, resolved)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return resolved, nil
+		}
+	}
+	return path, ErrPathNotAllowed
+}
+
+func convertLocalCommit(repo string, lc gitlocal.Commit) models.Commit {
+	return models.Commit{
+		Hash:       lc.SHA,
+		Message:    lc.Message,
+		Repository: repo,
+		Author:     lc.AuthorName,
+		Time:       lc.Date,
+		Files:      lc.Files,
+		Additions:  lc.Additions,
+		Deletions:  lc.Deletions,
+	}
+}
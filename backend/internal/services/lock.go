@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 仅当锁仍由自己持有时才删除，避免误删其他副本的锁
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// 仅当锁仍由自己持有时才续期
+var refreshLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+var ErrLockTimeout = errors.New("等待锁超时，请稍后重试")
+
+type redisLock struct {
+	client *redis.Client
+	key    string
+	token  string
+}
+
+func acquireLock(ctx context.Context, client *redis.Client, key string, ttl time.Duration) (*redisLock, bool, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, false, err
+	}
+	token := hex.EncodeToString(buf)
+
+	ok, err := client.SetNX(ctx, key, token, ttl).Result()
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	return &redisLock{client: client, key: key, token: token}, true, nil
+}
+
+// 轮询等待锁释放，超过wait仍未获取时返回ErrLockTimeout
+func waitForLock(ctx context.Context, client *redis.Client, key string, ttl, wait time.Duration) (*redisLock, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		lock, ok, err := acquireLock(ctx, client, key, ttl)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return lock, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, ErrLockTimeout
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func (l *redisLock) Release(ctx context.Context) error {
+	return releaseLockScript.Run(ctx, l.client, []string{l.key}, l.token).Err()
+}
+
+// 锁已过期或被其他副本持有时ok为false
+func (l *redisLock) Refresh(ctx context.Context, ttl time.Duration) (bool, error) {
+	n, err := refreshLockScript.Run(ctx, l.client, []string{l.key}, l.token, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// KeepAlive 每隔ttl/3续期一次，直到返回的ctx被取消。锁丢失时取消返回的ctx，持有者应停止操作
+func (l *redisLock) KeepAlive(ctx context.Context, ttl time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			ok, err := l.Refresh(ctx, ttl)
+			if err != nil {
+				// 暂时无法访问Redis时继续尝试，锁在ttl内仍然有效
+				log.Printf("Failed to refresh lock %s: %v", l.key, err)
+				continue
+			}
+			if !ok {
+				log.Printf("Lock %s lost, stopping", l.key)
+				cancel()
+				return
+			}
+		}
+	}()
+	return ctx, cancel
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"myvault-backend/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrManualEntryNotFound = errors.New("手动记录不存在")
+
+// CreateManualEntry 在某一天添加手动记录，当天没有活动时会先创建，返回更新后的活动
+func (s *ActivityService) CreateManualEntry(ctx context.Context, userID uint, req *models.ActivityRequest) (*models.Activity, error) {
+	date := req.Date.In(time.Local)
+	dateStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.Local)
+	dateEnd := dateStart.Add(24 * time.Hour)
+
+	return s.refreshDay(ctx, userID, dateStart, func() error {
+		var activity models.Activity
+		err := s.db.Where("user_id = ? AND date >= ? AND date < ?", userID, dateStart, dateEnd).
+			First(&activity).Error
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+
+		if err == gorm.ErrRecordNotFound {
+			activity = models.Activity{
+				UserID: userID,
+				Date:   dateStart,
+			}
+			if err := s.db.Create(&activity).Error; err != nil {
+				return err
+			}
+		}
+
+		entry := models.ManualEntry{
+			ActivityID: activity.ID,
+			UserID:     userID,
+			Title:      req.Title,
+			Body:       req.Body,
+			Duration:   req.Duration,
+			Tags:       req.Tags,
+		}
+		return s.db.Create(&entry).Error
+	})
+}
+
+func (s *ActivityService) UpdateManualEntry(ctx context.Context, userID, entryID uint, req *models.UpdateManualEntryRequest) (*models.Activity, error) {
+	entry, activity, err := s.getManualEntry(userID, entryID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Title != nil {
+		entry.Title = *req.Title
+	}
+	if req.Body != nil {
+		entry.Body = *req.Body
+	}
+	if req.Duration != nil {
+		entry.Duration = *req.Duration
+	}
+	if req.Tags != nil {
+		entry.Tags = *req.Tags
+	}
+
+	return s.refreshDay(ctx, userID, activity.Date, func() error {
+		return s.db.Save(entry).Error
+	})
+}
+
+func (s *ActivityService) DeleteManualEntry(ctx context.Context, userID, entryID uint) (*models.Activity, error) {
+	entry, activity, err := s.getManualEntry(userID, entryID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.refreshDay(ctx, userID, activity.Date, func() error {
+		return s.db.Delete(entry).Error
+	})
+}
+
+func (s *ActivityService) getManualEntry(userID, entryID uint) (*models.ManualEntry, *models.Activity, error) {
+	var entry models.ManualEntry
+	if err := s.db.Where("id = ? AND user_id = ?", entryID, userID).First(&entry).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil, ErrManualEntryNotFound
+		}
+		return nil, nil, err
+	}
+
+	var activity models.Activity
+	if err := s.db.First(&activity, entry.ActivityID).Error; err != nil {
+		return nil, nil, err
+	}
+
+	return &entry, &activity, nil
+}
+
+// 持有当天的锁执行update修改手动记录，再用当天已保存的记录重新计算统计和摘要
+func (s *ActivityService) refreshDay(ctx context.Context, userID uint, date time.Time, update func() error) (*models.Activity, error) {
+	activity, err := s.rewriteDay(ctx, userID, date, func(*ActivityData) error {
+		return update()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.hideRepositories(userID, activity); err != nil {
+		return nil, err
+	}
+	return activity, nil
+}
+
+// 持有当天的锁加载已保存的记录，由modify修改后重新写入，modify可为nil
+func (s *ActivityService) rewriteDay(ctx context.Context, userID uint, date time.Time, modify func(*ActivityData) error) (*models.Activity, error) {
+	date = date.In(time.Local)
+
+	var activity *models.Activity
+	err := s.withDayLock(ctx, userID, date, func() error {
+		data, err := s.loadDay(userID, date)
+		if err != nil {
+			return err
+		}
+		if modify != nil {
+			if err := modify(data); err != nil {
+				return err
+			}
+		}
+
+		activity, err = s.CreateOrUpdateActivity(ctx, userID, date, data)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return activity, nil
+}
+
+// 加载当天已保存的记录，用于修改后重新写入
+func (s *ActivityService) loadDay(userID uint, date time.Time) (*ActivityData, error) {
+	commits, err := getDayRecords[models.Commit](s.db, "commits", userID, date)
+	if err != nil {
+		return nil, err
+	}
+	dataSources, err := getDayRecords[models.DataSource](s.db, "data_sources", userID, date)
+	if err != nil {
+		return nil, err
+	}
+	pullRequests, err := getDayRecords[models.PullRequest](s.db, "pull_requests", userID, date)
+	if err != nil {
+		return nil, err
+	}
+	reviews, err := getDayRecords[models.Review](s.db, "reviews", userID, date)
+	if err != nil {
+		return nil, err
+	}
+	issueEvents, err := getDayRecords[models.IssueEvent](s.db, "issue_events", userID, date)
+	if err != nil {
+		return nil, err
+	}
+
+	// 记录会被删除后重新写入，清空主键
+	for i := range commits {
+		commits[i].ID, commits[i].ActivityID = 0, 0
+	}
+	for i := range dataSources {
+		dataSources[i].ID, dataSources[i].ActivityID = 0, 0
+	}
+	for i := range pullRequests {
+		pullRequests[i].ID, pullRequests[i].ActivityID = 0, 0
+	}
+	for i := range reviews {
+		reviews[i].ID, reviews[i].ActivityID = 0, 0
+	}
+	for i := range issueEvents {
+		issueEvents[i].ID, issueEvents[i].ActivityID = 0, 0
+	}
+
+	return &ActivityData{
+		Commits:      commits,
+		DataSources:  dataSources,
+		PullRequests: pullRequests,
+		Reviews:      reviews,
+		IssueEvents:  issueEvents,
+	}, nil
+}
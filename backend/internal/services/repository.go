@@ -0,0 +1,105 @@
+package services
+
+import (
+	"myvault-backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RepositoryService struct {
+	db *gorm.DB
+}
+
+func NewRepositoryService(db *gorm.DB) *RepositoryService {
+	return &RepositoryService{db: db}
+}
+
+func (s *RepositoryService) GetUserRepositories(userID uint) ([]models.Repository, error) {
+	var repos []models.Repository
+	if err := s.db.Where("user_id = ?", userID).
+		Order("source, full_name").
+		Find(&repos).Error; err != nil {
+		return nil, err
+	}
+
+	return repos, nil
+}
+
+func (s *RepositoryService) UpdateRepository(userID, repoID uint, req *models.UpdateRepositoryRequest) (*models.Repository, error) {
+	var repo models.Repository
+	if err := s.db.Where("id = ? AND user_id = ?", repoID, userID).First(&repo).Error; err != nil {
+		return nil, err
+	}
+
+	repo.Status = req.Status
+	if err := s.db.Save(&repo).Error; err != nil {
+		return nil, err
+	}
+
+	return &repo, nil
+}
+
+// UpsertRepositories 保存同步时发现的仓库，保留用户已设置的状态，返回full_name到状态的映射
+func (s *RepositoryService) UpsertRepositories(userID uint, source string, repos []models.Repository) (map[string]string, error) {
+	if len(repos) > 0 {
+		for i := range repos {
+			repos[i].UserID = userID
+			repos[i].Source = source
+			if repos[i].Status == "" {
+				repos[i].Status = models.RepositoryTracked
+			}
+		}
+
+		if err := s.db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "source"}, {Name: "full_name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"name", "description", "language", "private", "fork", "archived", "updated_at"}),
+		}).Create(&repos).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var stored []models.Repository
+	if err := s.db.Where("user_id = ? AND source = ?", userID, source).Find(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[string]string, len(stored))
+	for _, repo := range stored {
+		statuses[repo.FullName] = repo.Status
+	}
+
+	return statuses, nil
+}
+
+// 返回某个数据源下已保存的仓库，以full_name为键
+func (s *RepositoryService) RepositoryMap(userID uint, source string) (map[string]models.Repository, error) {
+	var repos []models.Repository
+	if err := s.db.Where("user_id = ? AND source = ?", userID, source).Find(&repos).Error; err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]models.Repository, len(repos))
+	for _, repo := range repos {
+		result[repo.FullName] = repo
+	}
+
+	return result, nil
+}
+
+// 返回用户设置为隐藏的仓库全名，供时间轴和摘要过滤
+func (s *RepositoryService) HiddenRepositories(userID uint) (map[string]bool, error) {
+	var names []string
+	if err := s.db.Model(&models.Repository{}).
+		Where("user_id = ? AND status = ?", userID, models.RepositoryHidden).
+		Pluck("full_name", &names).Error; err != nil {
+		return nil, err
+	}
+
+	hidden := make(map[string]bool, len(names))
+	for _, name := range names {
+		hidden[name] = true
+	}
+
+	return hidden, nil
+}
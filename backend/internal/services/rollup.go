@@ -0,0 +1,472 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"myvault-backend/internal/models"
+	"myvault-backend/pkg/ai"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidPeriod    = errors.New("无效的时间段")
+	ErrPeriodNotStarted = errors.New("时间段尚未开始")
+)
+
+// 修改汇总的提示词或格式时递增，使已保存的汇总重新生成
+const rollupPromptVersion = 1
+
+const rollupSystemPrompt = "你是一个专业的程序员工作总结助手。请根据提供的每日或每月活动摘要和统计数据，生成一份阶段性的工作总结，归纳主要完成的功能、持续推进的工作、修复的问题以及整体工作重点，适合用于周报和绩效回顾。不要逐日复述，请使用中文回答，语调要专业。"
+
+// 汇总中列出的仓库数
+const rollupTopRepositories = 10
+
+var weekdayNames = [...]string{"周日", "周一", "周二", "周三", "周四", "周五", "周六"}
+
+// RollupService 生成周、月、年的汇总摘要。周和月由每日摘要汇总，年由每月汇总再汇总，
+// 结果保存在PeriodSummary中，下级摘要和统计都没有变化时直接返回已保存的结果。
+// 查看时只计算统计，摘要由后台worker生成
+type RollupService struct {
+	db                *gorm.DB
+	redis             *redis.Client
+	aiService         *AIService
+	repositoryService *RepositoryService
+}
+
+func NewRollupService(db *gorm.DB, redis *redis.Client, aiService *AIService, repositoryService *RepositoryService) *RollupService {
+	return &RollupService{
+		db:                db,
+		redis:             redis,
+		aiService:         aiService,
+		repositoryService: repositoryService,
+	}
+}
+
+// 一个汇总时间段，end不包含
+type rollupPeriod struct {
+	periodType string
+	period     string
+	start      time.Time
+	end        time.Time
+}
+
+// 一条下级摘要，周和月为每日摘要，年为每月汇总
+type rollupItem struct {
+	label   string
+	summary string
+}
+
+// isoWeek格式为 2024-W05
+func (s *RollupService) GetWeekSummary(ctx context.Context, userID uint, isoWeek string) (*models.PeriodSummary, error) {
+	return s.getPeriodSummary(ctx, userID, models.PeriodWeek, isoWeek)
+}
+
+// month格式为 2024-05
+func (s *RollupService) GetMonthSummary(ctx context.Context, userID uint, month string) (*models.PeriodSummary, error) {
+	return s.getPeriodSummary(ctx, userID, models.PeriodMonth, month)
+}
+
+func (s *RollupService) GetYearSummary(ctx context.Context, userID uint, year string) (*models.PeriodSummary, error) {
+	return s.getPeriodSummary(ctx, userID, models.PeriodYear, year)
+}
+
+func (s *RollupService) getPeriodSummary(ctx context.Context, userID uint, periodType, value string) (*models.PeriodSummary, error) {
+	period, err := parsePeriod(periodType, value)
+	if err != nil {
+		return nil, err
+	}
+	return s.getSummary(ctx, userID, period)
+}
+
+func parsePeriod(periodType, value string) (rollupPeriod, error) {
+	switch periodType {
+	case models.PeriodWeek:
+		return parseWeekPeriod(value)
+	case models.PeriodMonth:
+		t, err := time.ParseInLocation("2006-01", value, time.Local)
+		if err != nil {
+			return rollupPeriod{}, ErrInvalidPeriod
+		}
+		return monthPeriod(t), nil
+	case models.PeriodYear:
+		t, err := time.ParseInLocation("2006", value, time.Local)
+		if err != nil {
+			return rollupPeriod{}, ErrInvalidPeriod
+		}
+		return rollupPeriod{
+			periodType: models.PeriodYear,
+			period:     t.Format("2006"),
+			start:      t,
+			end:        t.AddDate(1, 0, 0),
+		}, nil
+	}
+	return rollupPeriod{}, ErrInvalidPeriod
+}
+
+// ISO 8601周，1月4日所在的周为第一周，每周从周一开始
+func parseWeekPeriod(value string) (rollupPeriod, error) {
+	var year, week int
+	if _, err := fmt.Sscanf(value, "%d-W%d", &year, &week); err != nil || fmt.Sprintf("%04d-W%02d", year, week) != value {
+		return rollupPeriod{}, ErrInvalidPeriod
+	}
+
+	jan4 := time.Date(year, 1, 4, 0, 0, 0, 0, time.Local)
+	start := jan4.AddDate(0, 0, -((int(jan4.Weekday())+6)%7)+(week-1)*7)
+	// 排除第0周和不存在的第53周
+	if y, w := start.ISOWeek(); y != year || w != week {
+		return rollupPeriod{}, ErrInvalidPeriod
+	}
+
+	return rollupPeriod{
+		periodType: models.PeriodWeek,
+		period:     value,
+		start:      start,
+		end:        start.AddDate(0, 0, 7),
+	}, nil
+}
+
+func monthPeriod(t time.Time) rollupPeriod {
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.Local)
+	return rollupPeriod{
+		periodType: models.PeriodMonth,
+		period:     start.Format("2006-01"),
+		start:      start,
+		end:        start.AddDate(0, 1, 0),
+	}
+}
+
+// 返回已保存的汇总，统计或下级摘要变化时更新统计并把汇总加入生成队列，
+// 新的摘要生成前保留旧的摘要供展示
+func (s *RollupService) getSummary(ctx context.Context, userID uint, period rollupPeriod) (*models.PeriodSummary, error) {
+	if period.start.After(time.Now()) {
+		return nil, ErrPeriodNotStarted
+	}
+
+	summary, _, ready, err := s.buildSummary(ctx, userID, period, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing models.PeriodSummary
+	err = s.db.Where("user_id = ? AND period_type = ? AND period = ?", userID, period.periodType, period.period).
+		First(&existing).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if err == nil && existing.Fingerprint == summary.Fingerprint {
+		// 上次生成失败时，再次查看会重试
+		if existing.Status == models.SummaryFailed {
+			if err := s.db.Model(&existing).Updates(map[string]interface{}{
+				"status": models.SummaryPending,
+				"error":  "",
+			}).Error; err != nil {
+				return nil, err
+			}
+			existing.Status = models.SummaryPending
+			existing.Error = ""
+		}
+		if existing.Status == models.SummaryPending && ready {
+			s.enqueue(ctx, existing.ID)
+		}
+		return &existing, nil
+	}
+	if err == nil {
+		summary.Summary = existing.Summary
+		summary.AIGenerated = existing.AIGenerated
+	}
+
+	if summary.ActiveDays == 0 {
+		summary.Summary = "该时间段无编程活动"
+		summary.AIGenerated = false
+		summary.Status = models.SummarySkipped
+	} else {
+		summary.Status = models.SummaryPending
+	}
+
+	// 同一时间段可能被并发查看，按唯一索引更新已有的记录
+	if err := s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "period_type"}, {Name: "period"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"start_date", "end_date", "summary", "ai_generated", "status", "error",
+			"active_days", "commit_count", "pull_request_count", "review_count", "issue_count",
+			"total_time", "repositories", "fingerprint", "updated_at",
+		}),
+	}).Create(summary).Error; err != nil {
+		return nil, err
+	}
+	// 更新已有记录时数据库不返回ID
+	if err := s.db.Where("user_id = ? AND period_type = ? AND period = ?", userID, period.periodType, period.period).
+		First(summary).Error; err != nil {
+		return nil, err
+	}
+
+	// 年度总结等各月汇总生成后再生成，届时指纹已经变化
+	if summary.Status == models.SummaryPending && ready {
+		s.enqueue(ctx, summary.ID)
+	}
+
+	return summary, nil
+}
+
+// 用当前的数据计算汇总的统计、指纹和发送给模型的请求。prepare为true时年度总结会把
+// 需要更新的月份加入队列，ready表示下级摘要都已生成
+func (s *RollupService) buildSummary(ctx context.Context, userID uint, period rollupPeriod, prepare bool) (*models.PeriodSummary, *ai.SummaryRequest, bool, error) {
+	summary := &models.PeriodSummary{
+		UserID:     userID,
+		PeriodType: period.periodType,
+		Period:     period.period,
+		StartDate:  period.start,
+		EndDate:    period.end.AddDate(0, 0, -1),
+	}
+	if err := s.loadStats(userID, period, summary); err != nil {
+		return nil, nil, false, err
+	}
+
+	items, ready := []rollupItem(nil), true
+	var err error
+	if period.periodType == models.PeriodYear {
+		items, ready, err = s.monthItems(ctx, userID, period, prepare)
+	} else {
+		items, ready, err = s.dayItems(userID, period)
+	}
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	req := buildRollupRequest(period, summary, items)
+	summary.Fingerprint = summaryFingerprint(rollupPromptVersion, s.aiService.Identity(), req)
+	return summary, req, ready, nil
+}
+
+// 统计时间段内的活动，提交、PR、评审和Issue都只计算未隐藏的仓库，与发送给AI的内容保持一致
+func (s *RollupService) loadStats(userID uint, period rollupPeriod, summary *models.PeriodSummary) error {
+	var totals struct {
+		ActiveDays int
+		TotalTime  int
+	}
+	if err := s.db.Model(&models.Activity{}).
+		Select("COUNT(CASE WHEN has_activity THEN 1 END) AS active_days, "+
+			"COALESCE(SUM(total_time), 0) AS total_time").
+		Where("user_id = ? AND date >= ? AND date < ?", userID, period.start, period.end).
+		Scan(&totals).Error; err != nil {
+		return err
+	}
+
+	var rows []struct {
+		Repository  string
+		CommitCount int
+		Additions   int
+		Deletions   int
+	}
+	if err := s.periodRecords(&models.Commit{}, "commits", userID, period).
+		Select("commits.repository AS repository, COUNT(*) AS commit_count, " +
+			"COALESCE(SUM(commits.additions), 0) AS additions, COALESCE(SUM(commits.deletions), 0) AS deletions").
+		Group("commits.repository").
+		Order("commit_count DESC, repository").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	hidden, err := s.repositoryService.HiddenRepositories(userID)
+	if err != nil {
+		return err
+	}
+
+	counts := []struct {
+		model interface{}
+		table string
+		count *int
+	}{
+		{&models.PullRequest{}, "pull_requests", &summary.PullRequestCount},
+		{&models.Review{}, "reviews", &summary.ReviewCount},
+		{&models.IssueEvent{}, "issue_events", &summary.IssueCount},
+	}
+	for _, c := range counts {
+		var byRepo []struct {
+			Repository string
+			Count      int
+		}
+		if err := s.periodRecords(c.model, c.table, userID, period).
+			Select(c.table + ".repository AS repository, COUNT(*) AS count").
+			Group(c.table + ".repository").
+			Scan(&byRepo).Error; err != nil {
+			return err
+		}
+
+		*c.count = 0
+		for _, row := range byRepo {
+			if !hidden[row.Repository] {
+				*c.count += row.Count
+			}
+		}
+	}
+
+	summary.ActiveDays = totals.ActiveDays
+	summary.TotalTime = totals.TotalTime
+	summary.CommitCount = 0
+	summary.Repositories = nil
+	for _, row := range rows {
+		if hidden[row.Repository] {
+			continue
+		}
+		summary.CommitCount += row.CommitCount
+		summary.Repositories = append(summary.Repositories, models.RepositoryStat{
+			Repository: row.Repository,
+			Commits:    row.CommitCount,
+			Additions:  row.Additions,
+			Deletions:  row.Deletions,
+		})
+	}
+
+	return nil
+}
+
+// 时间段内属于该用户的某类记录
+func (s *RollupService) periodRecords(model interface{}, table string, userID uint, period rollupPeriod) *gorm.DB {
+	return s.db.Model(model).
+		Joins("JOIN activities ON activities.id = "+table+".activity_id").
+		Where("activities.user_id = ? AND activities.date >= ? AND activities.date < ? AND activities.deleted_at IS NULL",
+			userID, period.start, period.end)
+}
+
+// 有活动的每一天的摘要，摘要还未生成的日期跳过，生成后汇总的指纹随之变化。
+// 有日期的摘要正在排队或生成时ready为false，等它们生成后再汇总
+func (s *RollupService) dayItems(userID uint, period rollupPeriod) ([]rollupItem, bool, error) {
+	var activities []models.Activity
+	if err := s.db.Select("date", "summary", "summary_status").
+		Where("user_id = ? AND date >= ? AND date < ? AND has_activity = ?", userID, period.start, period.end, true).
+		Order("date").
+		Find(&activities).Error; err != nil {
+		return nil, false, err
+	}
+
+	var items []rollupItem
+	ready := true
+	for _, activity := range activities {
+		if activity.SummaryStatus == models.SummaryPending || activity.SummaryStatus == models.SummaryGenerating {
+			ready = false
+		}
+		if strings.TrimSpace(activity.Summary) == "" {
+			continue
+		}
+		date := activity.Date.In(time.Local)
+		items = append(items, rollupItem{
+			label:   date.Format("2006-01-02") + " " + weekdayNames[date.Weekday()],
+			summary: activity.Summary,
+		})
+	}
+	return items, ready, nil
+}
+
+// 已生成的每月汇总。prepare为true时通过getSummary检查各月，尚未保存或已过期的月份
+// 加入队列；否则只读取已保存的结果
+func (s *RollupService) monthItems(ctx context.Context, userID uint, period rollupPeriod, prepare bool) ([]rollupItem, bool, error) {
+	var items []rollupItem
+	ready := true
+	for month := period.start; month.Before(period.end) && !month.After(time.Now()); month = month.AddDate(0, 1, 0) {
+		var child *models.PeriodSummary
+		if prepare {
+			var err error
+			if child, err = s.getSummary(ctx, userID, monthPeriod(month)); err != nil {
+				return nil, false, err
+			}
+		} else {
+			child = &models.PeriodSummary{}
+			err := s.db.Where("user_id = ? AND period_type = ? AND period = ?", userID, models.PeriodMonth, month.Format("2006-01")).
+				First(child).Error
+			if err == gorm.ErrRecordNotFound {
+				continue
+			}
+			if err != nil {
+				return nil, false, err
+			}
+		}
+
+		if child.ActiveDays == 0 {
+			continue
+		}
+		// 状态为空的是加入状态前同步生成的汇总
+		if child.Status != models.SummaryDone && child.Status != "" {
+			ready = false
+			continue
+		}
+		items = append(items, rollupItem{
+			label:   strconv.Itoa(int(month.Month())) + "月",
+			summary: child.Summary,
+		})
+	}
+	return items, ready, nil
+}
+
+func buildRollupRequest(period rollupPeriod, summary *models.PeriodSummary, items []rollupItem) *ai.SummaryRequest {
+	req := &ai.SummaryRequest{System: rollupSystemPrompt}
+	var report, itemsTitle string
+	switch period.periodType {
+	case models.PeriodWeek:
+		req.Title, report, itemsTitle = "本周", "周报", "以下是本周每天的活动摘要："
+	case models.PeriodMonth:
+		req.Title, report, itemsTitle = "本月", "月报", "以下是本月每天的活动摘要："
+	default:
+		req.Title, report, itemsTitle = "本年度", "年度总结", "以下是本年度每月的工作总结："
+	}
+
+	if summary.ActiveDays > 0 {
+		req.Stats = append(req.Stats, fmt.Sprintf("%d天有活动", summary.ActiveDays))
+	}
+	if summary.TotalTime > 0 {
+		req.Stats = append(req.Stats, "累计"+formatDuration(summary.TotalTime*60))
+	}
+	counts := []struct {
+		n    int
+		unit string
+	}{
+		{summary.CommitCount, "次提交"},
+		{summary.PullRequestCount, "个Pull Request事件"},
+		{summary.ReviewCount, "次代码评审"},
+		{summary.IssueCount, "个Issue事件"},
+	}
+	for _, count := range counts {
+		if count.n > 0 {
+			req.Stats = append(req.Stats, fmt.Sprintf("%d%s", count.n, count.unit))
+		}
+	}
+
+	var promptBuilder strings.Builder
+	promptBuilder.WriteString(fmt.Sprintf("时间段: %s（%s 至 %s）\n",
+		period.period, summary.StartDate.Format("2006-01-02"), summary.EndDate.Format("2006-01-02")))
+	if len(req.Stats) > 0 {
+		promptBuilder.WriteString(fmt.Sprintf("统计: %s\n", strings.Join(req.Stats, "、")))
+	}
+	if len(summary.Repositories) > 0 {
+		promptBuilder.WriteString("主要仓库:\n")
+		for i, repo := range summary.Repositories {
+			if i >= rollupTopRepositories {
+				break
+			}
+			promptBuilder.WriteString(fmt.Sprintf("- %s: %d次提交, 新增%d行, 删除%d行\n",
+				repo.Repository, repo.Commits, repo.Additions, repo.Deletions))
+		}
+	}
+	promptBuilder.WriteString("\n")
+
+	if len(items) > 0 {
+		promptBuilder.WriteString(itemsTitle + "\n\n")
+		for _, item := range items {
+			promptBuilder.WriteString(fmt.Sprintf("### %s\n%s\n\n", item.label, strings.TrimSpace(item.summary)))
+
+			line, _, _ := strings.Cut(strings.TrimSpace(item.summary), "\n")
+			req.Highlights = append(req.Highlights, fmt.Sprintf("%s: %s", item.label, line))
+		}
+	}
+
+	promptBuilder.WriteString(fmt.Sprintf("请基于以上信息生成一份%s。", report))
+	req.Prompt = promptBuilder.String()
+	return req
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"myvault-backend/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	rollupQueueKey = "myvault:rollup:queue"
+	// 已在队列中的汇总ID，避免重复查看时多次加入
+	rollupQueuedKey = "myvault:rollup:queued"
+
+	rollupLockTTL = 15 * time.Minute
+)
+
+// 加入队列失败时汇总保持pending状态，下次查看时会再次加入
+func (s *RollupService) enqueue(ctx context.Context, summaryID uint) {
+	id := strconv.FormatUint(uint64(summaryID), 10)
+	added, err := s.redis.SAdd(ctx, rollupQueuedKey, id).Result()
+	if err == nil && added > 0 {
+		if err = s.redis.LPush(ctx, rollupQueueKey, id).Err(); err != nil {
+			s.redis.SRem(ctx, rollupQueuedKey, id)
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to enqueue period summary %d: %v", summaryID, err)
+	}
+}
+
+// Start 启动生成汇总的worker，阻塞直到ctx被取消。汇总按需生成，数量远少于每日摘要，
+// 使用单个worker，失败的汇总在下次查看时重试
+func (s *RollupService) Start(ctx context.Context) {
+	log.Println("Rollup worker started")
+
+	// 上次退出时正在生成的汇总
+	var summaries []models.PeriodSummary
+	if err := s.db.Select("id").Where("status = ?", models.SummaryGenerating).Find(&summaries).Error; err != nil {
+		log.Printf("Rollup worker: failed to recover summaries: %v", err)
+	}
+	for _, summary := range summaries {
+		if err := s.db.Model(&summary).Update("status", models.SummaryPending).Error; err != nil {
+			log.Printf("Rollup worker: failed to recover summary %d: %v", summary.ID, err)
+			continue
+		}
+		s.enqueue(ctx, summary.ID)
+	}
+
+	for ctx.Err() == nil {
+		result, err := s.redis.BRPop(ctx, summaryPollBlock, rollupQueueKey).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Rollup worker: failed to poll queue: %v", err)
+				time.Sleep(summaryPollBlock)
+			}
+			continue
+		}
+
+		// BRPop返回[key, value]
+		s.redis.SRem(ctx, rollupQueuedKey, result[1])
+		summaryID, err := strconv.ParseUint(result[1], 10, 64)
+		if err != nil {
+			log.Printf("Rollup worker: invalid summary id %q", result[1])
+			continue
+		}
+		s.runJob(ctx, uint(summaryID))
+	}
+}
+
+func (s *RollupService) runJob(ctx context.Context, summaryID uint) {
+	// 其他副本正在生成时跳过，汇总保持pending，下次查看时重新加入队列
+	lock, ok, err := acquireLock(ctx, s.redis, fmt.Sprintf("myvault:rollup:lock:%d", summaryID), rollupLockTTL)
+	if err != nil || !ok {
+		return
+	}
+	defer lock.Release(context.Background())
+
+	result := s.db.Model(&models.PeriodSummary{}).
+		Where("id = ? AND status = ?", summaryID, models.SummaryPending).
+		Update("status", models.SummaryGenerating)
+	if result.Error != nil {
+		log.Printf("Rollup worker: failed to update summary %d: %v", summaryID, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+
+	var saved models.PeriodSummary
+	if err := s.db.First(&saved, summaryID).Error; err != nil {
+		log.Printf("Rollup worker: failed to load summary %d: %v", summaryID, err)
+		return
+	}
+	period, err := parsePeriod(saved.PeriodType, saved.Period)
+	if err != nil {
+		s.failJob(summaryID, err)
+		return
+	}
+
+	// 使用最新的数据，年度总结只读取已生成的月份
+	summary, req, _, err := s.buildSummary(ctx, saved.UserID, period, false)
+	if err != nil {
+		s.failJob(summaryID, err)
+		return
+	}
+	text, err := s.aiService.GenerateSummary(ctx, req)
+	if err != nil {
+		s.failJob(summaryID, err)
+		return
+	}
+
+	summary.Summary = text
+	summary.AIGenerated = s.aiService.Generative()
+	summary.Status = models.SummaryDone
+	summary.Error = ""
+
+	// 生成期间数据变化时状态会回到pending，丢弃这次基于旧数据的结果。
+	// 使用结构体更新，仓库统计才会按serializer序列化
+	if err := s.db.Model(&models.PeriodSummary{}).
+		Where("id = ? AND status = ?", summaryID, models.SummaryGenerating).
+		Select("summary", "ai_generated", "status", "error", "active_days", "commit_count",
+			"pull_request_count", "review_count", "issue_count", "total_time", "repositories", "fingerprint").
+		Updates(summary).Error; err != nil {
+		log.Printf("Rollup worker: failed to save summary %d: %v", summaryID, err)
+	}
+}
+
+func (s *RollupService) failJob(summaryID uint, cause error) {
+	log.Printf("Rollup worker: failed to generate summary %d: %v", summaryID, cause)
+
+	if err := s.db.Model(&models.PeriodSummary{}).
+		Where("id = ? AND status = ?", summaryID, models.SummaryGenerating).
+		Updates(map[string]interface{}{
+			"status": models.SummaryFailed,
+			"error":  cause.Error(),
+		}).Error; err != nil {
+		log.Printf("Rollup worker: failed to update summary %d: %v", summaryID, err)
+	}
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"fmt"
+	"myvault-backend/internal/models"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseWeekPeriod(t *testing.T) {
+	tests := []struct {
+		value string
+		start string // 为空表示无效
+	}{
+		{"2024-W01", "2024-01-01"},
+		{"2024-W05", "2024-01-29"},
+		// 第一周从上一年开始
+		{"2025-W01", "2024-12-30"},
+		// 2020年有53周
+		{"2020-W53", "2020-12-28"},
+		{"2023-W52", "2023-12-25"},
+		// 2023年只有52周
+		{"2023-W53", ""},
+		{"2024-W00", ""},
+		{"2024-W54", ""},
+		{"2024-W5", ""},
+		{"2024-05", ""},
+		{"24-W05", ""},
+		{"2024-W05x", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		period, err := parseWeekPeriod(tt.value)
+		if tt.start == "" {
+			if err != ErrInvalidPeriod {
+				t.Errorf("parseWeekPeriod(%q) = %+v, %v, want ErrInvalidPeriod", tt.value, period, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseWeekPeriod(%q) returned %v", tt.value, err)
+			continue
+		}
+
+		if got := period.start.Format("2006-01-02"); got != tt.start {
+			t.Errorf("parseWeekPeriod(%q).start = %s, want %s", tt.value, got, tt.start)
+		}
+		if period.start.Weekday() != time.Monday || !period.end.Equal(period.start.AddDate(0, 0, 7)) {
+			t.Errorf("parseWeekPeriod(%q) = %s to %s, want a Monday-to-Monday week", tt.value, period.start, period.end)
+		}
+		if period.periodType != models.PeriodWeek || period.period != tt.value {
+			t.Errorf("parseWeekPeriod(%q) = %+v", tt.value, period)
+		}
+	}
+}
+
+func TestBuildRollupRequest(t *testing.T) {
+	week, err := parseWeekPeriod("2024-W05")
+	if err != nil {
+		t.Fatal(err)
+	}
+	month := monthPeriod(time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local))
+	year := rollupPeriod{periodType: models.PeriodYear, period: "2024"}
+
+	var repositories []models.RepositoryStat
+	for i := 0; i < rollupTopRepositories+2; i++ {
+		repositories = append(repositories, models.RepositoryStat{Repository: fmt.Sprintf("team/repo-%02d", i), Commits: 20 - i})
+	}
+
+	tests := []struct {
+		name           string
+		period         rollupPeriod
+		summary        models.PeriodSummary
+		items          []rollupItem
+		wantTitle      string
+		wantStats      []string
+		wantHighlights []string
+		wantPrompt     []string
+		notInPrompt    []string
+	}{
+		{
+			name:   "week",
+			period: week,
+			summary: models.PeriodSummary{
+				ActiveDays:  3,
+				TotalTime:   150,
+				CommitCount: 12,
+				ReviewCount: 2,
+				StartDate:   week.start,
+				EndDate:     week.end.AddDate(0, 0, -1),
+				Repositories: []models.RepositoryStat{
+					{Repository: "team/api", Commits: 12, Additions: 300, Deletions: 40},
+				},
+			},
+			items: []rollupItem{
+				{label: "2024-01-29 周一", summary: "完成登录接口\n补充测试"},
+				{label: "2024-01-31 周三", summary: "  修复分页问题  "},
+			},
+			wantTitle:      "本周",
+			wantStats:      []string{"3天有活动", "累计2小时30分钟", "12次提交", "2次代码评审"},
+			wantHighlights: []string{"2024-01-29 周一: 完成登录接口", "2024-01-31 周三: 修复分页问题"},
+			wantPrompt: []string{
+				"时间段: 2024-W05（2024-01-29 至 2024-02-04）",
+				"- team/api: 12次提交, 新增300行, 删除40行",
+				"以下是本周每天的活动摘要：",
+				"### 2024-01-29 周一\n完成登录接口\n补充测试",
+				"请基于以上信息生成一份周报。",
+			},
+			notInPrompt: []string{"Pull Request", "Issue"},
+		},
+		{
+			name:   "month limits repositories",
+			period: month,
+			summary: models.PeriodSummary{
+				ActiveDays:   1,
+				CommitCount:  1,
+				StartDate:    month.start,
+				EndDate:      month.end.AddDate(0, 0, -1),
+				Repositories: repositories,
+			},
+			wantTitle:   "本月",
+			wantStats:   []string{"1天有活动", "1次提交"},
+			wantPrompt:  []string{"team/repo-09", "请基于以上信息生成一份月报。"},
+			notInPrompt: []string{"team/repo-10", "以下是本月每天的活动摘要："},
+		},
+		{
+			name:           "year",
+			period:         year,
+			summary:        models.PeriodSummary{ActiveDays: 40, IssueCount: 1, PullRequestCount: 3},
+			items:          []rollupItem{{label: "5月", summary: "重构同步"}},
+			wantTitle:      "本年度",
+			wantStats:      []string{"40天有活动", "3个Pull Request事件", "1个Issue事件"},
+			wantHighlights: []string{"5月: 重构同步"},
+			wantPrompt:     []string{"以下是本年度每月的工作总结：", "请基于以上信息生成一份年度总结。"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := buildRollupRequest(tt.period, &tt.summary, tt.items)
+
+			if req.System != rollupSystemPrompt || req.Title != tt.wantTitle {
+				t.Errorf("System/Title = %q, %q", req.System, req.Title)
+			}
+			if !reflect.DeepEqual(req.Stats, tt.wantStats) {
+				t.Errorf("Stats = %q, want %q", req.Stats, tt.wantStats)
+			}
+			if !reflect.DeepEqual(req.Highlights, tt.wantHighlights) {
+				t.Errorf("Highlights = %q, want %q", req.Highlights, tt.wantHighlights)
+			}
+			for _, want := range tt.wantPrompt {
+				if !strings.Contains(req.Prompt, want) {
+					t.Errorf("prompt does not contain %q:\n%s", want, req.Prompt)
+				}
+			}
+			for _, unwanted := range tt.notInPrompt {
+				if strings.Contains(req.Prompt, unwanted) {
+					t.Errorf("prompt should not contain %q:\n%s", unwanted, req.Prompt)
+				}
+			}
+		})
+	}
+}
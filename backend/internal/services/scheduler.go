@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"myvault-backend/internal/models"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// 单个用户同步锁的过期时间，同步期间定期续期，进程异常退出后在此时间后释放
+const syncLockTTL = 30 * time.Minute
+
+type SyncScheduler struct {
+	db              *gorm.DB
+	redis           *redis.Client
+	activityService *ActivityService
+	interval        time.Duration
+	jitter          time.Duration
+}
+
+func NewSyncScheduler(db *gorm.DB, redis *redis.Client, activityService *ActivityService, interval, jitter time.Duration) *SyncScheduler {
+	return &SyncScheduler{
+		db:              db,
+		redis:           redis,
+		activityService: activityService,
+		interval:        interval,
+		jitter:          jitter,
+	}
+}
+
+// Start 阻塞运行，直到ctx被取消
+func (s *SyncScheduler) Start(ctx context.Context) {
+	log.Printf("Sync scheduler started, interval %s, jitter %s", s.interval, s.jitter)
+
+	for {
+		// 加入随机抖动，避免多个副本同时开始
+		timer := time.NewTimer(s.nextDelay())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.syncAll(ctx)
+	}
+}
+
+func (s *SyncScheduler) nextDelay() time.Duration {
+	if s.jitter <= 0 {
+		return s.interval
+	}
+	return s.interval + time.Duration(rand.Int63n(int64(s.jitter)))
+}
+
+func (s *SyncScheduler) syncAll(ctx context.Context) {
+	var users []models.User
+	if err := s.db.Find(&users).Error; err != nil {
+		log.Printf("Sync scheduler: failed to list users: %v", err)
+		return
+	}
+
+	for _, user := range users {
+		if ctx.Err() != nil {
+			return
+		}
+		// 是否绑定由各数据源自己判断
+		if !s.activityService.HasConnectedSource(&user) {
+			continue
+		}
+		if err := s.syncUser(ctx, user.ID); err != nil {
+			log.Printf("Sync scheduler: failed to sync user %d: %v", user.ID, err)
+		}
+	}
+}
+
+func (s *SyncScheduler) syncUser(ctx context.Context, userID uint) error {
+	// 多个副本的定时器互相错开，同步锁在同步结束后即释放，需记录本周期已同步过的用户。
+	// 有效期略短于同步间隔，避免本副本的下一轮因记录尚未过期而跳过
+	claimed, err := s.redis.SetNX(ctx, syncScheduledKey(userID), time.Now().Unix(), s.interval-s.interval/10).Result()
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		// 本周期内其他副本已同步该用户
+		return nil
+	}
+
+	lock, ok, err := acquireLock(ctx, s.redis, syncLockKey(userID), syncLockTTL)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// 其他副本正在同步该用户
+		return nil
+	}
+	defer lock.Release(context.Background())
+
+	ctx, stop := lock.KeepAlive(ctx, syncLockTTL)
+	defer stop()
+
+	return s.activityService.SyncActivities(ctx, userID, false)
+}
+
+func syncLockKey(userID uint) string {
+	return fmt.Sprintf("myvault:sync:lock:%d", userID)
+}
+
+func syncScheduledKey(userID uint) string {
+	return fmt.Sprintf("myvault:sync:scheduled:%d", userID)
+}
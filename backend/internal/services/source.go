@@ -0,0 +1,278 @@
+package services
+
+import (
+	"myvault-backend/internal/models"
+	"sort"
+	"time"
+)
+
+// ActivitySource 是一个活动数据源连接器，如GitHub、GitLab。
+// 新的数据源实现该接口并注册到SourceRegistry即可参与同步
+type ActivitySource interface {
+	// Name 标识数据源，同时用作同步游标的source、提交的Source和汇总DataSource的Type
+	Name() string
+	// Connected 判断用户是否绑定了该数据源
+	Connected(user *models.User) bool
+	// Fetch 获取时间窗口内的活动并转换为按日期分组的记录。
+	// 返回的batch不为nil时，error表示同步被提前中止（如限流），已获取的数据仍应写入
+	Fetch(sc *SyncContext) (*SyncBatch, error)
+}
+
+// SyncContext 是一次同步中传给数据源的参数和进度
+type SyncContext struct {
+	User *models.User
+	// 该数据源的同步游标，数据源可以更新其中的仓库游标
+	Cursor *models.SyncCursor
+	Force  bool
+	// 首次同步或强制同步时的时间窗口
+	From time.Time
+	To   time.Time
+
+	progress *models.SyncProgress
+	report   func()
+}
+
+// Since 返回增量同步的起点：强制同步或没有同步记录时为窗口起点，否则为上次同步时间
+func (sc *SyncContext) Since() time.Time {
+	if sc.Force || sc.Cursor.LastSyncAt == nil {
+		return sc.From
+	}
+	return *sc.Cursor.LastSyncAt
+}
+
+// RepositorySince 返回单个仓库的增量起点，有仓库游标时从上次看到的最新提交开始
+func (sc *SyncContext) RepositorySince(repoCursor *models.RepositoryCursor) time.Time {
+	if !sc.Force && repoCursor != nil {
+		return repoCursor.LastCommitAt
+	}
+	return sc.Since()
+}
+
+// AddRepositories 记录需要处理的仓库数量
+func (sc *SyncContext) AddRepositories(n int) {
+	sc.progress.ReposTotal += n
+	sc.report()
+}
+
+// RepositoryDone 记录一个仓库处理完成，err不为nil时记为失败
+func (sc *SyncContext) RepositoryDone(repo string, err error) {
+	sc.progress.ReposProcessed++
+	if err != nil {
+		sc.progress.Failures = append(sc.progress.Failures, models.SyncFailure{Repository: repo, Error: err.Error()})
+	}
+	sc.report()
+}
+
+// Fail 记录一次与具体仓库无关的失败，有失败时不推进该数据源的全局游标
+func (sc *SyncContext) Fail(name string, err error) {
+	sc.progress.Failures = append(sc.progress.Failures, models.SyncFailure{Repository: name, Error: err.Error()})
+	sc.report()
+}
+
+// SyncBatch 是一次同步获取到的数据，按本地日期分组
+type SyncBatch struct {
+	commits      map[string][]models.Commit
+	dataSources  map[string][]models.DataSource
+	pullRequests map[string][]models.PullRequest
+	reviews      map[string][]models.Review
+	issueEvents  map[string][]models.IssueEvent
+	// 数据源完整获取过的日期，这些日期上该数据源的旧记录即使没有新数据也会被清除
+	covered map[string]bool
+	// 需要从某天移除的提交hash
+	dropped map[string]map[string]bool
+	// 本次没有获取到的数据源记录的ID前缀，重写日期时保留这些旧记录
+	kept []string
+}
+
+func NewSyncBatch() *SyncBatch {
+	return &SyncBatch{
+		commits:      make(map[string][]models.Commit),
+		dataSources:  make(map[string][]models.DataSource),
+		pullRequests: make(map[string][]models.PullRequest),
+		reviews:      make(map[string][]models.Review),
+		issueEvents:  make(map[string][]models.IssueEvent),
+		covered:      make(map[string]bool),
+		dropped:      make(map[string]map[string]bool),
+	}
+}
+
+// Cover 声明from到to之间每一天的数据都已完整获取，用于日历等整体替换的数据源：
+// 会议被删除或移到其他日期后，原来那天没有新数据，也要清除旧记录
+func (b *SyncBatch) Cover(from, to time.Time) {
+	for day := from.In(time.Local); !day.After(to); day = day.AddDate(0, 0, 1) {
+		b.covered[localDay(day)] = true
+	}
+}
+
+// Keep 声明ID以prefix开头的数据源记录本次没有获取到（如获取失败的日历），
+// 整体替换数据源时保留这些记录的旧数据
+func (b *SyncBatch) Keep(prefix string) {
+	b.kept = append(b.kept, prefix)
+}
+
+func (b *SyncBatch) dropCommit(day, hash string) {
+	if b.dropped[day] == nil {
+		b.dropped[day] = make(map[string]bool)
+	}
+	b.dropped[day][hash] = true
+}
+
+func (b *SyncBatch) AddCommit(commit models.Commit) {
+	day := localDay(commit.Time)
+	b.commits[day] = append(b.commits[day], commit)
+}
+
+// AddDataSource 添加某一天的数据源记录，Data需为带id字段的JSON数组，同类型的记录会按id合并
+func (b *SyncBatch) AddDataSource(date time.Time, dataSource models.DataSource) {
+	day := localDay(date)
+	b.dataSources[day] = append(b.dataSources[day], dataSource)
+}
+
+func (b *SyncBatch) AddPullRequest(pr models.PullRequest) {
+	day := localDay(pr.Time)
+	b.pullRequests[day] = append(b.pullRequests[day], pr)
+}
+
+func (b *SyncBatch) AddReview(review models.Review) {
+	day := localDay(review.Time)
+	b.reviews[day] = append(b.reviews[day], review)
+}
+
+func (b *SyncBatch) AddIssueEvent(issue models.IssueEvent) {
+	day := localDay(issue.Time)
+	b.issueEvents[day] = append(b.issueEvents[day], issue)
+}
+
+func (b *SyncBatch) hasData(day string) bool {
+	return len(b.commits[day]) > 0 || len(b.dataSources[day]) > 0 || len(b.pullRequests[day]) > 0 ||
+		len(b.reviews[day]) > 0 || len(b.issueEvents[day]) > 0 || len(b.dropped[day]) > 0
+}
+
+// 有新数据的日期、Cover声明的日期和需要移除提交的日期
+func (b *SyncBatch) days() []string {
+	seen := make(map[string]bool)
+	var days []string
+	add := func(day string) {
+		if !seen[day] {
+			seen[day] = true
+			days = append(days, day)
+		}
+	}
+	for day := range b.commits {
+		add(day)
+	}
+	for day := range b.dataSources {
+		add(day)
+	}
+	for day := range b.pullRequests {
+		add(day)
+	}
+	for day := range b.reviews {
+		add(day)
+	}
+	for day := range b.issueEvents {
+		add(day)
+	}
+	for day := range b.covered {
+		add(day)
+	}
+	for day := range b.dropped {
+		add(day)
+	}
+	sort.Strings(days)
+	return days
+}
+
+// SourceRegistry 保存已注册的数据源，按注册顺序同步
+type SourceRegistry struct {
+	sources []ActivitySource
+}
+
+func NewSourceRegistry(sources ...ActivitySource) *SourceRegistry {
+	registry := &SourceRegistry{}
+	for _, source := range sources {
+		registry.Register(source)
+	}
+	return registry
+}
+
+// Register 注册数据源，同名数据源会被替换
+func (r *SourceRegistry) Register(source ActivitySource) {
+	for i, existing := range r.sources {
+		if existing.Name() == source.Name() {
+			r.sources[i] = source
+			return
+		}
+	}
+	r.sources = append(r.sources, source)
+}
+
+func (r *SourceRegistry) Get(name string) (ActivitySource, bool) {
+	for _, source := range r.sources {
+		if source.Name() == name {
+			return source, true
+		}
+	}
+	return nil, false
+}
+
+// Connected 返回用户已绑定的数据源
+func (r *SourceRegistry) Connected(user *models.User) []ActivitySource {
+	var connected []ActivitySource
+	for _, source := range r.sources {
+		if source.Connected(user) {
+			connected = append(connected, source)
+		}
+	}
+	return connected
+}
+
+func localDay(t time.Time) string {
+	return t.In(time.Local).Format("2006-01-02")
+}
+
+// 根据用户设置过滤fork、已归档和被忽略的仓库
+func shouldSyncRepository(user *models.User, repo models.Repository) bool {
+	if repo.Fork && !user.IncludeForks {
+		return false
+	}
+	if repo.Archived && !user.IncludeArchived {
+		return false
+	}
+	return repo.Status != models.RepositoryIgnored
+}
+
+// 保存发现的仓库并按用户设置过滤，返回需要同步的仓库和所有仓库的状态
+func discoverRepositories(repositoryService *RepositoryService, user *models.User, source string, discovered []models.Repository) ([]models.Repository, map[string]string, error) {
+	statuses, err := repositoryService.UpsertRepositories(user.ID, source, discovered)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var repos []models.Repository
+	for _, repo := range discovered {
+		repo.Status = statuses[repo.FullName]
+		if shouldSyncRepository(user, repo) {
+			repos = append(repos, repo)
+		}
+	}
+
+	return repos, statuses, nil
+}
+
+// 仓库游标以仓库全名为键，便于数据源按仓库增量获取，结束时用saveRepositoryCursors写回
+func repositoryCursors(cursor *models.SyncCursor) map[string]*models.RepositoryCursor {
+	repoCursors := make(map[string]*models.RepositoryCursor)
+	for _, repoCursor := range cursor.RepoCursors {
+		repoCursor := repoCursor
+		repoCursors[repoCursor.Repository] = &repoCursor
+	}
+	return repoCursors
+}
+
+func saveRepositoryCursors(cursor *models.SyncCursor, repoCursors map[string]*models.RepositoryCursor) {
+	cursor.RepoCursors = cursor.RepoCursors[:0]
+	for _, repoCursor := range repoCursors {
+		cursor.RepoCursors = append(cursor.RepoCursors, *repoCursor)
+	}
+}
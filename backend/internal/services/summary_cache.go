@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"myvault-backend/internal/models"
+	"myvault-backend/pkg/ai"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// 数据回到之前的某个状态（如撤销后又恢复的提交）时可以复用当时的摘要
+const summaryCacheTTL = 30 * 24 * time.Hour
+
+// 生成的摘要和对应的指纹。aiGenerated表示内容来自模型，包括之前由同一模型生成、按指纹复用的结果
+type activitySummary struct {
+	text        string
+	fingerprint string
+	aiGenerated bool
+}
+
+// 用已保存的记录生成摘要，隐藏仓库的内容不发送给AI。
+// 指纹与上次生成时相同或在Redis中命中时不调用模型，force为true时总是重新生成
+func (s *ActivityService) summarizeActivity(ctx context.Context, activityID uint, force bool) (*activitySummary, error) {
+	var activity models.Activity
+	if err := s.db.Preload("Commits").
+		Preload("DataSources").
+		Preload("PullRequests").
+		Preload("Reviews").
+		Preload("IssueEvents").
+		Preload("ManualEntries", func(db *gorm.DB) *gorm.DB { return db.Order("created_at, id") }).
+		First(&activity, activityID).Error; err != nil {
+		return nil, err
+	}
+
+	hidden, err := s.repositoryService.HiddenRepositories(activity.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	data := filterHidden(&ActivityData{
+		Commits:       activity.Commits,
+		DataSources:   activity.DataSources,
+		PullRequests:  activity.PullRequests,
+		Reviews:       activity.Reviews,
+		IssueEvents:   activity.IssueEvents,
+		ManualEntries: activity.ManualEntries,
+	}, hidden)
+	// 所有记录都在隐藏仓库中
+	if data.empty() {
+		return &activitySummary{text: "今日无编程活动"}, nil
+	}
+
+	sortActivityData(data)
+	req := buildSummaryRequest(data)
+	fingerprint := summaryFingerprint(summaryPromptVersion, s.aiService.Identity(), req)
+	cacheKey := summaryCacheKey(activity.UserID, fingerprint)
+	// 指纹包含Provider和模型，复用的摘要与这次生成的来源相同
+	result := &activitySummary{fingerprint: fingerprint, aiGenerated: s.aiService.Generative()}
+
+	if !force {
+		if activity.SummaryFingerprint == fingerprint && activity.Summary != "" {
+			result.text = activity.Summary
+			return result, nil
+		}
+		summary, err := s.redis.Get(ctx, cacheKey).Result()
+		if err == nil {
+			result.text = summary
+			return result, nil
+		}
+		if err != redis.Nil {
+			log.Printf("Failed to read summary cache for activity %d: %v", activityID, err)
+		}
+	}
+
+	summary, err := s.aiService.GenerateSummary(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	result.text = summary
+
+	if err := s.redis.Set(ctx, cacheKey, summary, summaryCacheTTL).Err(); err != nil {
+		log.Printf("Failed to cache summary for activity %d: %v", activityID, err)
+	}
+
+	return result, nil
+}
+
+// 指纹由提示词版本、Provider和模型（见AIService.Identity）以及发送给模型的全部内容计算，
+// 内容来自当天的记录，记录和模型不变时指纹不变
+func summaryFingerprint(version int, identity string, req *ai.SummaryRequest) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "v%d\n%s\n%s\n", version, identity, req.System)
+	fmt.Fprintf(h, "%s\n%s\n", req.Title, req.Prompt)
+	fmt.Fprintf(h, "%s\n%s\n", strings.Join(req.Stats, "\x00"), strings.Join(req.Highlights, "\x00"))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// 数据库不保证返回顺序，同步时写入的顺序也取决于各数据源，按时间和外部ID排序后再构建提示词
+func sortActivityData(data *ActivityData) {
+	sort.SliceStable(data.Commits, func(i, j int) bool {
+		a, b := data.Commits[i], data.Commits[j]
+		return byTimeThenKey(a.Time, b.Time, a.Repository+"\x00"+a.Hash, b.Repository+"\x00"+b.Hash)
+	})
+	sort.SliceStable(data.PullRequests, func(i, j int) bool {
+		a, b := data.PullRequests[i], data.PullRequests[j]
+		return byTimeThenKey(a.Time, b.Time, a.ExternalID, b.ExternalID)
+	})
+	sort.SliceStable(data.Reviews, func(i, j int) bool {
+		a, b := data.Reviews[i], data.Reviews[j]
+		return byTimeThenKey(a.Time, b.Time, a.ExternalID, b.ExternalID)
+	})
+	sort.SliceStable(data.IssueEvents, func(i, j int) bool {
+		a, b := data.IssueEvents[i], data.IssueEvents[j]
+		return byTimeThenKey(a.Time, b.Time, a.ExternalID, b.ExternalID)
+	})
+	sort.SliceStable(data.DataSources, func(i, j int) bool {
+		a, b := data.DataSources[i], data.DataSources[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Data < b.Data
+	})
+}
+
+func byTimeThenKey(ti, tj time.Time, ki, kj string) bool {
+	if !ti.Equal(tj) {
+		return ti.Before(tj)
+	}
+	return ki < kj
+}
+
+func summaryCacheKey(userID uint, fingerprint string) string {
+	return fmt.Sprintf("myvault:summary:cache:%d:%s", userID, fingerprint)
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"fmt"
+	"myvault-backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestSummaryFingerprint(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+
+	// 每次返回新的切片，sortActivityData会原地排序
+	build := func(reversed bool) *ActivityData {
+		data := &ActivityData{
+			Commits: []models.Commit{
+				{Hash: "a1", Repository: "team/api", Message: "Add login", Time: at(9, 0), Additions: 10},
+				// 与上一条同一时间，排序时按仓库和hash区分
+				{Hash: "b1", Repository: "team/web", Message: "Login page", Time: at(9, 0), Additions: 20},
+				{Hash: "a2", Repository: "team/api", Message: "Fix token refresh", Time: at(14, 30), Deletions: 5},
+			},
+			PullRequests: []models.PullRequest{
+				{ExternalID: "team/api#1:opened", Repository: "team/api", Number: 1, Title: "Login", Action: "opened", Time: at(10, 0)},
+				{ExternalID: "team/web#2:merged", Repository: "team/web", Number: 2, Title: "Login page", Action: "merged", Time: at(10, 0)},
+			},
+			Reviews: []models.Review{
+				{ExternalID: "review:1", Repository: "team/web", PullNumber: 3, PullTitle: "Refactor", State: "approved", Time: at(11, 0)},
+			},
+			IssueEvents: []models.IssueEvent{
+				{ExternalID: "team/api#4:closed", Repository: "team/api", Number: 4, Title: "Token expires", Action: "closed", Time: at(15, 0)},
+			},
+		}
+		if reversed {
+			for i, j := 0, len(data.Commits)-1; i < j; i, j = i+1, j-1 {
+				data.Commits[i], data.Commits[j] = data.Commits[j], data.Commits[i]
+			}
+			data.PullRequests[0], data.PullRequests[1] = data.PullRequests[1], data.PullRequests[0]
+		}
+		return data
+	}
+
+	fingerprint := func(data *ActivityData, hidden map[string]bool, version int, identity string) string {
+		visible := filterHidden(data, hidden)
+		sortActivityData(visible)
+		return summaryFingerprint(version, identity, buildSummaryRequest(visible))
+	}
+
+	base := fingerprint(build(false), nil, summaryPromptVersion, "openai/gpt-4o-mini")
+
+	edited := build(false)
+	edited.Commits[2].Message = "Fix token refresh race"
+
+	tests := []struct {
+		name     string
+		data     *ActivityData
+		hidden   map[string]bool
+		version  int
+		identity string
+		same     bool
+	}{
+		{"same records", build(false), nil, summaryPromptVersion, "openai/gpt-4o-mini", true},
+		{"different record order", build(true), nil, summaryPromptVersion, "openai/gpt-4o-mini", true},
+		{"hidden repository without records", build(true), map[string]bool{"team/other": true}, summaryPromptVersion, "openai/gpt-4o-mini", true},
+		{"hidden repository", build(false), map[string]bool{"team/web": true}, summaryPromptVersion, "openai/gpt-4o-mini", false},
+		{"different model", build(false), nil, summaryPromptVersion, "openai/gpt-4o", false},
+		{"different provider", build(false), nil, summaryPromptVersion, "ollama/gpt-4o-mini", false},
+		{"new prompt version", build(false), nil, summaryPromptVersion + 1, "openai/gpt-4o-mini", false},
+		{"edited commit", edited, nil, summaryPromptVersion, "openai/gpt-4o-mini", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fingerprint(tt.data, tt.hidden, tt.version, tt.identity)
+			if (got == base) != tt.same {
+				t.Errorf("fingerprint = %s, base %s, want same %v", got, base, tt.same)
+			}
+		})
+	}
+}
+
+func TestSortActivityData(t *testing.T) {
+	at := time.Date(2024, 5, 1, 9, 0, 0, 0, time.Local)
+	data := &ActivityData{
+		Commits: []models.Commit{
+			{Hash: "c", Repository: "team/b", Time: at.Add(time.Hour)},
+			{Hash: "b", Repository: "team/b", Time: at},
+			{Hash: "z", Repository: "team/a", Time: at},
+		},
+		DataSources: []models.DataSource{
+			{Type: "webhook", Data: `[{"id":"2"}]`},
+			{Type: "calendar", Data: `[{"id":"1"}]`},
+			{Type: "webhook", Data: `[{"id":"1"}]`},
+		},
+	}
+
+	sortActivityData(data)
+
+	var hashes, sources []string
+	for _, commit := range data.Commits {
+		hashes = append(hashes, commit.Hash)
+	}
+	for _, ds := range data.DataSources {
+		sources = append(sources, ds.Type+ds.Data)
+	}
+	if got := fmt.Sprint(hashes); got != "[z b c]" {
+		t.Errorf("commit order = %s", got)
+	}
+	if got := fmt.Sprint(sources); got != `[calendar[{"id":"1"}] webhook[{"id":"1"}] webhook[{"id":"2"}]]` {
+		t.Errorf("data source order = %s", got)
+	}
+}
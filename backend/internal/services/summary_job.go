@@ -0,0 +1,331 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"myvault-backend/internal/models"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+var ErrActivityNotFound = errors.New("活动不存在")
+
+const (
+	summaryQueueKey = "myvault:summary:queue"
+	// 已在队列中的活动ID，避免同一天被重复加入
+	summaryQueuedKey = "myvault:summary:queued"
+	// 等待重试的活动ID，score为可以重试的时间
+	summaryRetryKey = "myvault:summary:retry"
+	// 用户要求重新生成的活动ID，生成成功前不使用缓存
+	summaryForceKey = "myvault:summary:force"
+
+	summaryPollBlock = 5 * time.Second
+	// 需要覆盖模型超时和重试的总时长
+	summaryLockTTL = 15 * time.Minute
+	// 其他worker正在处理同一活动时，稍后再试
+	summaryBusyDelay = 5 * time.Second
+	summaryRetryBase = time.Minute
+	summaryRetryMax  = time.Hour
+)
+
+// SummaryJobService 在后台为活动生成AI摘要。同步只写入记录并把活动标记为pending，
+// 模型变慢或不可用时不影响数据写入，失败的摘要按指数退避重试
+type SummaryJobService struct {
+	db              *gorm.DB
+	redis           *redis.Client
+	activityService *ActivityService
+	workers         int
+	maxAttempts     int
+}
+
+func NewSummaryJobService(db *gorm.DB, redis *redis.Client, activityService *ActivityService, workers, maxAttempts int) *SummaryJobService {
+	if workers < 1 {
+		workers = 1
+	}
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &SummaryJobService{
+		db:              db,
+		redis:           redis,
+		activityService: activityService,
+		workers:         workers,
+		maxAttempts:     maxAttempts,
+	}
+}
+
+func enqueueSummary(ctx context.Context, client *redis.Client, activityID uint) error {
+	id := strconv.FormatUint(uint64(activityID), 10)
+	added, err := client.SAdd(ctx, summaryQueuedKey, id).Result()
+	if err != nil || added == 0 {
+		return err
+	}
+	if err := client.LPush(ctx, summaryQueueKey, id).Err(); err != nil {
+		client.SRem(ctx, summaryQueuedKey, id)
+		return err
+	}
+	return nil
+}
+
+func scheduleSummaryRetry(ctx context.Context, client *redis.Client, activityID uint, delay time.Duration) error {
+	return client.ZAdd(ctx, summaryRetryKey, redis.Z{
+		Score:  float64(time.Now().Add(delay).Unix()),
+		Member: strconv.FormatUint(uint64(activityID), 10),
+	}).Err()
+}
+
+// 第n次失败后等待 1m·2^(n-1)，最多1小时
+func summaryRetryDelay(attempts int) time.Duration {
+	delay := summaryRetryBase
+	for i := 1; i < attempts && delay < summaryRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > summaryRetryMax {
+		delay = summaryRetryMax
+	}
+	return delay
+}
+
+// Start 恢复未完成的摘要并启动worker，阻塞直到ctx被取消
+func (s *SummaryJobService) Start(ctx context.Context) {
+	log.Printf("Summary workers started, concurrency %d", s.workers)
+
+	if err := s.recover(ctx); err != nil {
+		log.Printf("Summary worker: failed to recover pending summaries: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.promoteRetries(ctx)
+	}()
+	for i := 0; i < s.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+// 进程退出或入队失败时，数据库中仍有pending/generating的活动，启动时重新加入队列
+func (s *SummaryJobService) recover(ctx context.Context) error {
+	var activities []models.Activity
+	if err := s.db.Select("id", "summary_status", "summary_attempts").
+		Where("summary_status IN ?", []string{models.SummaryPending, models.SummaryGenerating, models.SummaryFailed}).
+		Find(&activities).Error; err != nil {
+		return err
+	}
+
+	for _, activity := range activities {
+		var err error
+		if activity.SummaryStatus == models.SummaryFailed {
+			if activity.SummaryAttempts >= s.maxAttempts {
+				continue
+			}
+			err = scheduleSummaryRetry(ctx, s.redis, activity.ID, summaryRetryDelay(activity.SummaryAttempts))
+		} else {
+			err = enqueueSummary(ctx, s.redis, activity.ID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 定期把到期的重试移回队列
+func (s *SummaryJobService) promoteRetries(ctx context.Context) {
+	ticker := time.NewTicker(summaryPollBlock)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ids, err := s.redis.ZRangeByScore(ctx, summaryRetryKey, &redis.ZRangeBy{
+			Min: "-inf",
+			Max: strconv.FormatInt(time.Now().Unix(), 10),
+		}).Result()
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Summary worker: failed to poll retries: %v", err)
+			}
+			continue
+		}
+
+		for _, id := range ids {
+			// 多个副本同时轮询时，只有移除成功的一方入队
+			removed, err := s.redis.ZRem(ctx, summaryRetryKey, id).Result()
+			if err != nil || removed == 0 {
+				continue
+			}
+			activityID, err := strconv.ParseUint(id, 10, 64)
+			if err != nil {
+				continue
+			}
+			if err := enqueueSummary(ctx, s.redis, uint(activityID)); err != nil {
+				log.Printf("Summary worker: failed to enqueue retry for activity %s: %v", id, err)
+			}
+		}
+	}
+}
+
+func (s *SummaryJobService) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		result, err := s.redis.BRPop(ctx, summaryPollBlock, summaryQueueKey).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Summary worker: failed to poll queue: %v", err)
+				time.Sleep(summaryPollBlock)
+			}
+			continue
+		}
+
+		// BRPop返回[key, value]
+		s.redis.SRem(ctx, summaryQueuedKey, result[1])
+		activityID, err := strconv.ParseUint(result[1], 10, 64)
+		if err != nil {
+			log.Printf("Summary worker: invalid activity id %q", result[1])
+			continue
+		}
+		s.runJob(ctx, uint(activityID))
+	}
+}
+
+func (s *SummaryJobService) runJob(ctx context.Context, activityID uint) {
+	lock, ok, err := acquireLock(ctx, s.redis, summaryLockKey(activityID), summaryLockTTL)
+	if err != nil || !ok {
+		// 其他worker正在生成，数据可能已经变化，稍后重新检查
+		if err := scheduleSummaryRetry(ctx, s.redis, activityID, summaryBusyDelay); err != nil {
+			log.Printf("Summary worker: failed to reschedule activity %d: %v", activityID, err)
+		}
+		return
+	}
+	defer lock.Release(context.Background())
+
+	result := s.db.Model(&models.Activity{}).
+		Where("id = ? AND summary_status IN ?", activityID, []string{models.SummaryPending, models.SummaryGenerating, models.SummaryFailed}).
+		Update("summary_status", models.SummaryGenerating)
+	if result.Error != nil {
+		log.Printf("Summary worker: failed to update activity %d: %v", activityID, result.Error)
+		return
+	}
+	// 活动已删除、已生成或无需生成
+	if result.RowsAffected == 0 {
+		return
+	}
+
+	force, err := s.redis.SIsMember(ctx, summaryForceKey, strconv.FormatUint(uint64(activityID), 10)).Result()
+	if err != nil {
+		s.failJob(activityID, err)
+		return
+	}
+
+	summary, err := s.activityService.summarizeActivity(ctx, activityID, force)
+	if err != nil {
+		s.failJob(activityID, err)
+		return
+	}
+
+	// 生成期间数据被重新同步时状态会回到pending，丢弃这次基于旧数据的结果
+	if err := s.db.Model(&models.Activity{}).
+		Where("id = ? AND summary_status = ?", activityID, models.SummaryGenerating).
+		Updates(map[string]interface{}{
+			"summary":             summary.text,
+			"summary_fingerprint": summary.fingerprint,
+			"ai_generated":        summary.aiGenerated,
+			"summary_status":      models.SummaryDone,
+			"summary_error":       "",
+			"summary_attempts":    0,
+		}).Error; err != nil {
+		log.Printf("Summary worker: failed to save summary for activity %d: %v", activityID, err)
+		return
+	}
+	if force {
+		s.redis.SRem(context.Background(), summaryForceKey, strconv.FormatUint(uint64(activityID), 10))
+	}
+}
+
+func (s *SummaryJobService) failJob(activityID uint, cause error) {
+	ctx := context.Background()
+	log.Printf("Summary worker: failed to generate summary for activity %d: %v", activityID, cause)
+
+	var activity models.Activity
+	if err := s.db.Select("id", "summary_attempts").First(&activity, activityID).Error; err != nil {
+		return
+	}
+	attempts := activity.SummaryAttempts + 1
+
+	result := s.db.Model(&models.Activity{}).
+		Where("id = ? AND summary_status = ?", activityID, models.SummaryGenerating).
+		Updates(map[string]interface{}{
+			"summary_status":   models.SummaryFailed,
+			"summary_error":    cause.Error(),
+			"summary_attempts": attempts,
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return
+	}
+
+	if attempts < s.maxAttempts {
+		if err := scheduleSummaryRetry(ctx, s.redis, activityID, summaryRetryDelay(attempts)); err != nil {
+			log.Printf("Summary worker: failed to schedule retry for activity %d: %v", activityID, err)
+		}
+	}
+}
+
+// RegenerateSummary 重新生成摘要，即使数据没有变化也会调用模型，不使用缓存
+func (s *ActivityService) RegenerateSummary(ctx context.Context, userID, activityID uint) (*models.Activity, error) {
+	activity, err := s.GetActivityByID(userID, activityID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrActivityNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	status := models.SummaryPending
+	if !activity.HasActivity {
+		status = models.SummarySkipped
+	} else if err := s.redis.SAdd(ctx, summaryForceKey, strconv.FormatUint(uint64(activity.ID), 10)).Err(); err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(activity).Updates(map[string]interface{}{
+		"summary_status":   status,
+		"summary_error":    "",
+		"summary_attempts": 0,
+	}).Error; err != nil {
+		return nil, err
+	}
+	activity.SummaryStatus = status
+	activity.SummaryError = ""
+	activity.SummaryAttempts = 0
+
+	if status == models.SummaryPending {
+		if err := enqueueSummary(ctx, s.redis, activity.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return activity, nil
+}
+
+func summaryLockKey(activityID uint) string {
+	return fmt.Sprintf("myvault:summary:lock:%d", activityID)
+}
@@ -0,0 +1,333 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"myvault-backend/internal/models"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	syncQueueKey = "myvault:sync:queue"
+	// worker取出的任务先移到处理列表，完成后再删除，worker异常退出时启动时放回队列
+	syncProcessingKey = "myvault:sync:processing"
+	syncJobTTL        = 24 * time.Hour
+	syncPollBlock     = 5 * time.Second
+	// 用户正在被定时同步时，任务等待该时间后重新排队
+	syncRetryDelay = 5 * time.Second
+	// 运行中的任务定期更新心跳，超过syncHeartbeatTimeout没有心跳时视为worker已经异常退出
+	syncHeartbeatInterval = time.Minute
+	syncHeartbeatTimeout  = 3 * syncHeartbeatInterval
+)
+
+var ErrSyncJobNotFound = errors.New("同步任务不存在")
+
+type SyncJobService struct {
+	redis           *redis.Client
+	activityService *ActivityService
+	workers         int
+}
+
+func NewSyncJobService(redis *redis.Client, activityService *ActivityService, workers int) *SyncJobService {
+	if workers < 1 {
+		workers = 1
+	}
+	return &SyncJobService{
+		redis:           redis,
+		activityService: activityService,
+		workers:         workers,
+	}
+}
+
+func (s *SyncJobService) EnqueueSync(userID uint, force bool) (*models.SyncJob, error) {
+	ctx := context.Background()
+
+	// 同一用户已有未完成的任务时直接返回该任务
+	if jobID, err := s.redis.Get(ctx, activeJobKey(userID)).Result(); err == nil {
+		if job, err := s.loadJob(ctx, jobID); err == nil && !isStaleJob(job) &&
+			(job.State == models.SyncJobQueued || job.State == models.SyncJobRunning) {
+			return job, nil
+		}
+	} else if err != redis.Nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+
+	job := &models.SyncJob{
+		ID:        hex.EncodeToString(buf),
+		UserID:    userID,
+		Force:     force,
+		State:     models.SyncJobQueued,
+		CreatedAt: time.Now(),
+	}
+	job.HeartbeatAt = job.CreatedAt
+
+	if err := s.saveJob(ctx, job); err != nil {
+		return nil, err
+	}
+	if err := s.redis.Set(ctx, activeJobKey(userID), job.ID, syncJobTTL).Err(); err != nil {
+		return nil, err
+	}
+	if err := s.redis.LPush(ctx, syncQueueKey, job.ID).Err(); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (s *SyncJobService) GetSyncJob(userID uint, jobID string) (*models.SyncJob, error) {
+	job, err := s.loadJob(context.Background(), jobID)
+	if err == redis.Nil {
+		return nil, ErrSyncJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 不允许查看其他用户的任务
+	if job.UserID != userID {
+		return nil, ErrSyncJobNotFound
+	}
+
+	return job, nil
+}
+
+// Start 启动固定数量的worker消费队列，阻塞直到ctx被取消
+func (s *SyncJobService) Start(ctx context.Context) {
+	log.Printf("Sync workers started, concurrency %d", s.workers)
+
+	s.recoverJobs(ctx)
+
+	var wg sync.WaitGroup
+	for i := 0; i < s.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *SyncJobService) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		jobID, err := s.redis.BLMove(ctx, syncQueueKey, syncProcessingKey, "RIGHT", "LEFT", syncPollBlock).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Sync worker: failed to poll queue: %v", err)
+				time.Sleep(syncPollBlock)
+			}
+			continue
+		}
+
+		s.runJob(ctx, jobID)
+		if err := s.redis.LRem(context.Background(), syncProcessingKey, 1, jobID).Err(); err != nil {
+			log.Printf("Sync worker: failed to remove job %s from processing list: %v", jobID, err)
+		}
+	}
+}
+
+// 将异常退出的worker留在处理列表中的任务放回队列。仍有心跳的任务正在其他副本上运行，保留不动
+func (s *SyncJobService) recoverJobs(ctx context.Context) {
+	jobIDs, err := s.redis.LRange(ctx, syncProcessingKey, 0, -1).Result()
+	if err != nil {
+		log.Printf("Sync worker: failed to list processing jobs: %v", err)
+		return
+	}
+
+	for _, jobID := range jobIDs {
+		job, err := s.loadJob(ctx, jobID)
+		if err != nil && err != redis.Nil {
+			log.Printf("Sync worker: failed to load job %s: %v", jobID, err)
+			continue
+		}
+		// 已过期或已结束的任务直接移出处理列表
+		if err == redis.Nil || job.State == models.SyncJobSucceeded || job.State == models.SyncJobFailed {
+			s.redis.LRem(ctx, syncProcessingKey, 0, jobID)
+			continue
+		}
+
+		if job.State == models.SyncJobRunning && !isStaleJob(job) {
+			continue
+		}
+
+		job.State = models.SyncJobQueued
+		job.StartedAt = nil
+		job.Progress = models.SyncProgress{}
+		job.HeartbeatAt = time.Now()
+		if err := s.saveJob(ctx, job); err != nil {
+			log.Printf("Sync worker: failed to save job %s: %v", jobID, err)
+			continue
+		}
+		// 先放回队列再移出处理列表，中途退出时任务不会丢失
+		if err := s.redis.LPush(ctx, syncQueueKey, jobID).Err(); err != nil {
+			log.Printf("Sync worker: failed to requeue job %s: %v", jobID, err)
+			continue
+		}
+		s.redis.LRem(ctx, syncProcessingKey, 1, jobID)
+		log.Printf("Sync worker: requeued job %s for user %d", jobID, job.UserID)
+	}
+}
+
+func (s *SyncJobService) runJob(ctx context.Context, jobID string) {
+	job, err := s.loadJob(ctx, jobID)
+	if err != nil {
+		log.Printf("Sync worker: failed to load job %s: %v", jobID, err)
+		return
+	}
+	// 只执行排队中的任务，重复入队的任务不会重复执行
+	if job.State != models.SyncJobQueued {
+		return
+	}
+
+	lock, ok, err := acquireLock(ctx, s.redis, syncLockKey(job.UserID), syncLockTTL)
+	if err != nil {
+		s.finishJob(job, err)
+		return
+	}
+	if !ok {
+		// 定时同步正在进行，等它结束后再执行
+		s.requeueJob(ctx, job)
+		return
+	}
+	defer lock.Release(context.Background())
+
+	ctx, stop := lock.KeepAlive(ctx, syncLockTTL)
+	defer stop()
+
+	startedAt := time.Now()
+	job.State = models.SyncJobRunning
+	job.StartedAt = &startedAt
+
+	// 进度更新和心跳都会保存任务，加锁避免并发修改
+	var mu sync.Mutex
+	save := func(update func()) {
+		mu.Lock()
+		defer mu.Unlock()
+		update()
+		job.HeartbeatAt = time.Now()
+		s.saveJob(ctx, job)
+	}
+	save(func() {})
+	stopHeartbeat := s.heartbeat(ctx, save)
+
+	err = s.activityService.SyncActivitiesWithProgress(ctx, job.UserID, job.Force, func(progress models.SyncProgress) {
+		save(func() { job.Progress = progress })
+	})
+	// 先停止心跳，避免结束后的心跳覆盖最终状态
+	stopHeartbeat()
+	s.finishJob(job, err)
+}
+
+// 每隔syncHeartbeatInterval保存一次任务，返回的函数停止心跳并等待正在进行的保存完成
+func (s *SyncJobService) heartbeat(ctx context.Context, save func(update func())) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(syncHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				save(func() {})
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// 等待syncRetryDelay后放回队列，避免队列中只有该任务时被反复取出。
+// worker退出时立即放回，任务不会因为随后移出处理列表而丢失
+func (s *SyncJobService) requeueJob(ctx context.Context, job *models.SyncJob) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(syncRetryDelay):
+	}
+
+	job.HeartbeatAt = time.Now()
+	if err := s.saveJob(context.Background(), job); err != nil {
+		log.Printf("Sync worker: failed to save job %s: %v", job.ID, err)
+	}
+	if err := s.redis.LPush(context.Background(), syncQueueKey, job.ID).Err(); err != nil {
+		s.finishJob(job, err)
+	}
+}
+
+func (s *SyncJobService) finishJob(job *models.SyncJob, err error) {
+	defer s.redis.Del(context.Background(), activeJobKey(job.UserID))
+
+	finishedAt := time.Now()
+	job.FinishedAt = &finishedAt
+	if err != nil {
+		job.State = models.SyncJobFailed
+		job.Error = err.Error()
+		log.Printf("Sync worker: job %s for user %d failed: %v", job.ID, job.UserID, err)
+	} else {
+		job.State = models.SyncJobSucceeded
+	}
+
+	if err := s.saveJob(context.Background(), job); err != nil {
+		log.Printf("Sync worker: failed to save job %s: %v", job.ID, err)
+	}
+}
+
+func (s *SyncJobService) loadJob(ctx context.Context, jobID string) (*models.SyncJob, error) {
+	data, err := s.redis.Get(ctx, jobKey(jobID)).Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var job models.SyncJob
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func (s *SyncJobService) saveJob(ctx context.Context, job *models.SyncJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return s.redis.Set(ctx, jobKey(job.ID), data, syncJobTTL).Err()
+}
+
+// 运行中超过syncHeartbeatTimeout、排队中超过syncLockTTL没有心跳的任务视为worker已经异常退出或任务已丢失，
+// 不再阻止用户发起新的同步
+func isStaleJob(job *models.SyncJob) bool {
+	if job.State == models.SyncJobRunning {
+		return time.Since(job.HeartbeatAt) > syncHeartbeatTimeout
+	}
+	return time.Since(job.HeartbeatAt) > syncLockTTL
+}
+
+func jobKey(jobID string) string {
+	return "myvault:sync:job:" + jobID
+}
+
+func activeJobKey(userID uint) string {
+	return fmt.Sprintf("myvault:sync:active:%d", userID)
+}
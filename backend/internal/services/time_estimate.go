@@ -0,0 +1,46 @@
+package services
+
+import (
+	"myvault-backend/internal/models"
+	"sort"
+	"time"
+)
+
+// TimeEstimator 根据提交时间估算编码时长，思路与git-hours相同：
+// 相邻提交间隔小于SessionGap时视为同一段编码，计入间隔；
+// 否则视为新的一段，为这段的第一次提交补上FirstCommitPadding
+type TimeEstimator struct {
+	SessionGap         time.Duration
+	FirstCommitPadding time.Duration
+}
+
+func NewTimeEstimator(sessionGap, firstCommitPadding time.Duration) *TimeEstimator {
+	return &TimeEstimator{
+		SessionGap:         sessionGap,
+		FirstCommitPadding: firstCommitPadding,
+	}
+}
+
+func (e *TimeEstimator) Estimate(commits []models.Commit) time.Duration {
+	if len(commits) == 0 {
+		return 0
+	}
+
+	times := make([]time.Time, 0, len(commits))
+	for _, commit := range commits {
+		times = append(times, commit.Time)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	total := e.FirstCommitPadding
+	for i := 1; i < len(times); i++ {
+		gap := times[i].Sub(times[i-1])
+		if gap < e.SessionGap {
+			total += gap
+		} else {
+			total += e.FirstCommitPadding
+		}
+	}
+
+	return total
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"myvault-backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestTimeEstimator(t *testing.T) {
+	estimator := NewTimeEstimator(2*time.Hour, 30*time.Minute)
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+
+	tests := []struct {
+		name  string
+		times []time.Time
+		want  time.Duration
+	}{
+		{"no commits", nil, 0},
+		{"single commit", []time.Time{at(10, 0)}, 30 * time.Minute},
+		{"same session", []time.Time{at(10, 0), at(10, 40), at(11, 30)}, 30*time.Minute + 90*time.Minute},
+		// 间隔等于SessionGap时视为新的一段
+		{"gap at threshold", []time.Time{at(9, 0), at(11, 0)}, 30*time.Minute + 30*time.Minute},
+		{"gap below threshold", []time.Time{at(9, 0), at(10, 59)}, 30*time.Minute + 119*time.Minute},
+		{"two sessions", []time.Time{at(9, 0), at(9, 20), at(14, 0), at(14, 10)}, 30*time.Minute + 20*time.Minute + 30*time.Minute + 10*time.Minute},
+		{"unsorted", []time.Time{at(14, 10), at(9, 0), at(14, 0), at(9, 20)}, 30*time.Minute + 20*time.Minute + 30*time.Minute + 10*time.Minute},
+		{"same timestamp", []time.Time{at(10, 0), at(10, 0)}, 30 * time.Minute},
+		// 跨过零点的提交仍按实际间隔计算
+		{"across midnight", []time.Time{at(23, 40), at(24, 10)}, 30*time.Minute + 30*time.Minute},
+		{"across midnight after a long gap", []time.Time{at(20, 0), at(23, 50), at(24, 5)}, 30*time.Minute + 30*time.Minute + 15*time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var commits []models.Commit
+			for _, commitTime := range tt.times {
+				commits = append(commits, models.Commit{Time: commitTime})
+			}
+			if got := estimator.Estimate(commits); got != tt.want {
+				t.Errorf("Estimate = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// 不补时长时单次提交计为0
+func TestTimeEstimatorWithoutPadding(t *testing.T) {
+	estimator := NewTimeEstimator(time.Hour, 0)
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local)
+
+	if got := estimator.Estimate([]models.Commit{{Time: start}}); got != 0 {
+		t.Errorf("single commit = %s, want 0", got)
+	}
+	commits := []models.Commit{{Time: start}, {Time: start.Add(45 * time.Minute)}, {Time: start.Add(3 * time.Hour)}}
+	if got := estimator.Estimate(commits); got != 45*time.Minute {
+		t.Errorf("Estimate = %s, want 45m", got)
+	}
+}
@@ -30,9 +30,10 @@ func (s *UserService) CreateUser(req *models.CreateUserRequest) (*models.User, e
 	}
 
 	user := &models.User{
-		Username: req.Username,
-		Email:    req.Email,
-		Password: string(hashedPassword),
+		Username:     req.Username,
+		Email:        req.Email,
+		Password:     string(hashedPassword),
+		IncludeForks: true,
 	}
 
 	if err := s.db.Create(user).Error; err != nil {
@@ -77,6 +78,26 @@ func (s *UserService) UpdateUser(id uint, req *models.UpdateUserRequest) (*model
 		user.Avatar = req.Avatar
 	}
 
+	if req.IncludeForks != nil {
+		user.IncludeForks = *req.IncludeForks
+	}
+
+	if req.IncludeArchived != nil {
+		user.IncludeArchived = *req.IncludeArchived
+	}
+
+	if req.AllBranches != nil {
+		user.AllBranches = *req.AllBranches
+	}
+
+	if req.LocalRepoPaths != nil {
+		user.LocalRepoPaths = *req.LocalRepoPaths
+	}
+
+	if req.LocalGitEmail != nil {
+		user.LocalGitEmail = *req.LocalGitEmail
+	}
+
 	if err := s.db.Save(&user).Error; err != nil {
 		return nil, err
 	}
@@ -84,11 +105,17 @@ func (s *UserService) UpdateUser(id uint, req *models.UpdateUserRequest) (*model
 	return &user, nil
 }
 
-func (s *UserService) GetOrCreateGithubUser(githubID, username, email, avatar string) (*models.User, error) {
+func (s *UserService) GetOrCreateGithubUser(githubID, username, email, avatar, accessToken string) (*models.User, error) {
 	var user models.User
 	
-	// 首先尝试通过GitHub ID查找用户
+	// 首先尝试通过GitHub ID查找用户，并刷新保存的访问令牌
 	if err := s.db.Where("github_id = ?", githubID).First(&user).Error; err == nil {
+		if user.AccessToken != accessToken {
+			user.AccessToken = accessToken
+			if err := s.db.Model(&user).Update("access_token", accessToken).Error; err != nil {
+				return nil, err
+			}
+		}
 		return &user, nil
 	}
 
@@ -100,6 +127,8 @@ func (s *UserService) GetOrCreateGithubUser(githubID, username, email, avatar st
 		Avatar:         avatar,
 		GithubUsername: username,
 		GithubID:       githubID,
+		AccessToken:    accessToken,
+		IncludeForks:   true,
 	}
 
 	if err := s.db.Create(&user).Error; err != nil {
@@ -109,6 +138,63 @@ func (s *UserService) GetOrCreateGithubUser(githubID, username, email, avatar st
 	return &user, nil
 }
 
+// 保存GitLab绑定信息，令牌已由调用方验证
+func (s *UserService) ConnectGitlab(id uint, username, email, baseURL, accessToken string) (*models.User, error) {
+	return s.updateUser(id, func(user *models.User) {
+		user.GitlabUsername = username
+		user.GitlabEmail = email
+		user.GitlabBaseURL = baseURL
+		user.GitlabToken = accessToken
+	})
+}
+
+func (s *UserService) DisconnectGitlab(id uint) (*models.User, error) {
+	return s.ConnectGitlab(id, "", "", "", "")
+}
+
+// 保存Gitea绑定信息，令牌已由调用方验证
+func (s *UserService) ConnectGitea(id uint, username, email, baseURL, accessToken string) (*models.User, error) {
+	return s.updateUser(id, func(user *models.User) {
+		user.GiteaUsername = username
+		user.GiteaEmail = email
+		user.GiteaBaseURL = baseURL
+		user.GiteaToken = accessToken
+	})
+}
+
+func (s *UserService) DisconnectGitea(id uint) (*models.User, error) {
+	return s.ConnectGitea(id, "", "", "", "")
+}
+
+// 保存WakaTime绑定信息，API Key已由调用方验证
+func (s *UserService) ConnectWakatime(id uint, username, baseURL, apiKey string) (*models.User, error) {
+	return s.updateUser(id, func(user *models.User) {
+		user.WakatimeUsername = username
+		user.WakatimeBaseURL = baseURL
+		user.WakatimeAPIKey = apiKey
+	})
+}
+
+func (s *UserService) DisconnectWakatime(id uint) (*models.User, error) {
+	return s.ConnectWakatime(id, "", "", "")
+}
+
+// 加载用户，由update修改后保存
+func (s *UserService) updateUser(id uint, update func(*models.User)) (*models.User, error) {
+	var user models.User
+	if err := s.db.First(&user, id).Error; err != nil {
+		return nil, err
+	}
+
+	update(&user)
+
+	if err := s.db.Save(&user).Error; err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
 func (s *UserService) VerifyPassword(user *models.User, password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
 }
\ No newline at end of file
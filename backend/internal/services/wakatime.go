@@ -0,0 +1,44 @@
+package services
+
+import (
+	"myvault-backend/pkg/wakatime"
+	"time"
+)
+
+type WakatimeService struct {
+	// 用户未指定API地址时使用配置的默认地址，用户填写的地址只允许访问公网
+	instances instanceResolver
+}
+
+func NewWakatimeService(defaultBaseURL string) *WakatimeService {
+	return &WakatimeService{instances: newInstanceResolver(defaultBaseURL)}
+}
+
+func (s *WakatimeService) client(baseURL string) (*wakatime.Client, error) {
+	baseURL, httpClient, err := s.instances.resolve(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	client := wakatime.NewClient(baseURL)
+	if httpClient != nil {
+		client.SetHTTPClient(httpClient)
+	}
+	return client, nil
+}
+
+func (s *WakatimeService) GetCurrentUser(baseURL, apiKey string) (*wakatime.User, error) {
+	client, err := s.client(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetCurrentUser(apiKey)
+}
+
+func (s *WakatimeService) GetSummaries(baseURL, apiKey string, start, end time.Time) ([]wakatime.Summary, error) {
+	client, err := s.client(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetSummaries(apiKey, start, end)
+}
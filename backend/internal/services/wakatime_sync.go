@@ -0,0 +1,131 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"myvault-backend/internal/models"
+	"myvault-backend/pkg/wakatime"
+	"strings"
+	"time"
+)
+
+// 编码时长数据源的类型，每天一条记录，Data为只含一个codingTimeRecord的数组
+const codingTimeSourceType = "wakatime"
+
+// 每天的编码时长及按项目、语言、编辑器的分布
+type codingTimeRecord struct {
+	ID           string           `json:"id"` // 日期，重新同步时按日期替换
+	TotalSeconds int              `json:"total_seconds"`
+	Projects     []codingTimeItem `json:"projects"`
+	Languages    []codingTimeItem `json:"languages"`
+	Editors      []codingTimeItem `json:"editors"`
+}
+
+type codingTimeItem struct {
+	Name         string  `json:"name"`
+	TotalSeconds int     `json:"total_seconds"`
+	Percent      float64 `json:"percent"`
+}
+
+// WakatimeSource 从WakaTime兼容的服务导入每天的编码时长
+type WakatimeSource struct {
+	wakatimeService *WakatimeService
+}
+
+func NewWakatimeSource(wakatimeService *WakatimeService) *WakatimeSource {
+	return &WakatimeSource{wakatimeService: wakatimeService}
+}
+
+func (s *WakatimeSource) Name() string {
+	return codingTimeSourceType
+}
+
+func (s *WakatimeSource) Connected(user *models.User) bool {
+	return user.WakatimeAPIKey != ""
+}
+
+// 从上次同步的当天开始重新获取，当天的时长会随编码持续增加
+func (s *WakatimeSource) Fetch(sc *SyncContext) (*SyncBatch, error) {
+	summaries, err := s.wakatimeService.GetSummaries(sc.User.WakatimeBaseURL, sc.User.WakatimeAPIKey, sc.Since(), sc.To)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := NewSyncBatch()
+	for _, summary := range summaries {
+		if summary.GrandTotal.TotalSeconds <= 0 {
+			continue
+		}
+
+		date, err := time.ParseInLocation("2006-01-02", summary.Range.Date, time.Local)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := json.Marshal([]codingTimeRecord{convertWakatimeSummary(summary)})
+		if err != nil {
+			return nil, err
+		}
+		batch.AddDataSource(date, models.DataSource{Type: codingTimeSourceType, Data: string(data)})
+	}
+
+	return batch, nil
+}
+
+func convertWakatimeSummary(summary wakatime.Summary) codingTimeRecord {
+	convert := func(items []wakatime.Item) []codingTimeItem {
+		converted := make([]codingTimeItem, 0, len(items))
+		for _, item := range items {
+			converted = append(converted, codingTimeItem{
+				Name:         item.Name,
+				TotalSeconds: int(item.TotalSeconds),
+				Percent:      item.Percent,
+			})
+		}
+		return converted
+	}
+
+	return codingTimeRecord{
+		ID:           summary.Range.Date,
+		TotalSeconds: int(summary.GrandTotal.TotalSeconds),
+		Projects:     convert(summary.Projects),
+		Languages:    convert(summary.Languages),
+		Editors:      convert(summary.Editors),
+	}
+}
+
+// 当天的编码时长，没有导入时返回nil
+func (d *ActivityData) codingTime() *codingTimeRecord {
+	for _, ds := range d.DataSources {
+		if ds.Type != codingTimeSourceType {
+			continue
+		}
+		var records []codingTimeRecord
+		if err := json.Unmarshal([]byte(ds.Data), &records); err != nil || len(records) == 0 {
+			continue
+		}
+		return &records[len(records)-1]
+	}
+	return nil
+}
+
+// 格式化为"X小时Y分钟"
+func formatDuration(seconds int) string {
+	minutes := seconds / 60
+	if minutes < 60 {
+		return fmt.Sprintf("%d分钟", minutes)
+	}
+	return fmt.Sprintf("%d小时%d分钟", minutes/60, minutes%60)
+}
+
+// 取时长最多的前几项，格式化为"名称 时长"列表
+func formatCodingTimeItems(items []codingTimeItem, limit int) string {
+	var parts []string
+	for i, item := range items {
+		if i >= limit {
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s %s", item.Name, formatDuration(item.TotalSeconds)))
+	}
+	return strings.Join(parts, ", ")
+}
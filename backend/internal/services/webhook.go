@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"myvault-backend/internal/models"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook不存在")
+	ErrInvalidSignature = errors.New("webhook签名无效")
+	ErrStaleEvent       = errors.New("事件时间超出允许范围")
+)
+
+// 外部事件数据源的类型，Data为当天的webhookRecord数组
+const webhookSourceType = "webhook"
+
+// 签名格式与GitHub相同：sha256=<请求体的HMAC-SHA256十六进制值>
+const webhookSignaturePrefix = "sha256="
+
+// 事件时间与服务器时间相差超过该值时拒绝，防止截获的请求被重放
+const webhookMaxSkew = 5 * time.Minute
+
+type webhookRecord struct {
+	ID       string                 `json:"id"` // webhook ID:事件id，未提供事件id时由事件内容生成
+	Hook     string                 `json:"hook"`
+	Type     string                 `json:"type"`
+	Title    string                 `json:"title"`
+	URL      string                 `json:"url,omitempty"`
+	Time     time.Time              `json:"time"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+type WebhookService struct {
+	db              *gorm.DB
+	activityService *ActivityService
+}
+
+func NewWebhookService(db *gorm.DB, activityService *ActivityService) *WebhookService {
+	return &WebhookService{
+		db:              db,
+		activityService: activityService,
+	}
+}
+
+func (s *WebhookService) GetUserWebhooks(userID uint) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	if err := s.db.Where("user_id = ?", userID).Order("created_at").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// CreateWebhook 创建webhook，返回的secret只在此时可见
+func (s *WebhookService) CreateWebhook(userID uint, req *models.CreateWebhookRequest) (*models.Webhook, string, error) {
+	token, secret, err := newWebhookCredentials()
+	if err != nil {
+		return nil, "", err
+	}
+
+	webhook := models.Webhook{
+		UserID: userID,
+		Name:   req.Name,
+		Token:  token,
+		Secret: secret,
+	}
+	if err := s.db.Create(&webhook).Error; err != nil {
+		return nil, "", err
+	}
+
+	return &webhook, secret, nil
+}
+
+// RotateWebhook 重新生成token和secret，旧的接收地址立即失效
+func (s *WebhookService) RotateWebhook(userID, webhookID uint) (*models.Webhook, string, error) {
+	var webhook models.Webhook
+	if err := s.db.Where("id = ? AND user_id = ?", webhookID, userID).First(&webhook).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, "", ErrWebhookNotFound
+		}
+		return nil, "", err
+	}
+
+	token, secret, err := newWebhookCredentials()
+	if err != nil {
+		return nil, "", err
+	}
+
+	webhook.Token = token
+	webhook.Secret = secret
+	if err := s.db.Save(&webhook).Error; err != nil {
+		return nil, "", err
+	}
+
+	return &webhook, secret, nil
+}
+
+// DeleteWebhook 吊销webhook，已接收的事件保留
+func (s *WebhookService) DeleteWebhook(userID, webhookID uint) error {
+	result := s.db.Where("id = ? AND user_id = ?", webhookID, userID).Delete(&models.Webhook{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// VerifyWebhook 根据token查找webhook并校验请求体的签名
+func (s *WebhookService) VerifyWebhook(token, signature string, body []byte) (*models.Webhook, error) {
+	var webhook models.Webhook
+	if err := s.db.Where("token = ?", token).First(&webhook).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+
+	if err := verifyWebhookSignature(webhook.Secret, signature, body); err != nil {
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+func verifyWebhookSignature(secret, signature string, body []byte) error {
+	if !strings.HasPrefix(signature, webhookSignaturePrefix) {
+		return ErrInvalidSignature
+	}
+	received, err := hex.DecodeString(strings.TrimPrefix(signature, webhookSignaturePrefix))
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(received, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// ReceiveEvent 将事件写入事件时间所在日期的活动，重复发送的事件只保留一条
+func (s *WebhookService) ReceiveEvent(ctx context.Context, webhook *models.Webhook, event *models.WebhookEvent) (*models.Activity, error) {
+	record, err := newWebhookRecord(webhook, event, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	activity, err := s.activityService.addWebhookRecord(ctx, webhook.UserID, record)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	s.db.Model(webhook).Update("last_used_at", &now)
+
+	return activity, nil
+}
+
+func newWebhookRecord(webhook *models.Webhook, event *models.WebhookEvent, now time.Time) (webhookRecord, error) {
+	// 时间戳包含在签名的请求体中，超出范围的请求视为重放
+	if skew := now.Sub(event.Timestamp); skew > webhookMaxSkew || skew < -webhookMaxSkew {
+		return webhookRecord{}, ErrStaleEvent
+	}
+
+	id := event.ID
+	if id == "" {
+		// 未提供id时按事件内容生成，相同的事件重复发送不会产生多条记录。时间统一为UTC，
+		// 同一时刻以不同时区表示时也得到相同的id
+		normalized := *event
+		normalized.Timestamp = event.Timestamp.UTC()
+		encoded, err := json.Marshal(&normalized)
+		if err != nil {
+			return webhookRecord{}, err
+		}
+		sum := sha256.Sum256(encoded)
+		id = hex.EncodeToString(sum[:8])
+	}
+
+	return webhookRecord{
+		ID:       fmt.Sprintf("%d:%s", webhook.ID, id),
+		Hook:     webhook.Name,
+		Type:     event.Type,
+		Title:    event.Title,
+		URL:      event.URL,
+		Time:     event.Timestamp.In(time.Local),
+		Metadata: event.Metadata,
+	}, nil
+}
+
+// 将外部事件与当天已有的事件合并后重新写入，同id的记录会被替换。rewriteDay持有当天的锁，并发接收的事件不会互相覆盖
+func (s *ActivityService) addWebhookRecord(ctx context.Context, userID uint, record webhookRecord) (*models.Activity, error) {
+	date := time.Date(record.Time.Year(), record.Time.Month(), record.Time.Day(), 0, 0, 0, 0, time.Local)
+
+	encoded, err := json.Marshal([]webhookRecord{record})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.rewriteDay(ctx, userID, date, func(data *ActivityData) error {
+		var err error
+		data.DataSources, err = mergeDataSources(data.DataSources, []models.DataSource{{Type: webhookSourceType, Data: string(encoded)}}, nil)
+		return err
+	})
+}
+
+// 当天接收的外部事件，按时间排序
+func (d *ActivityData) webhookEvents() []webhookRecord {
+	var events []webhookRecord
+	for _, ds := range d.DataSources {
+		if ds.Type != webhookSourceType {
+			continue
+		}
+		var records []webhookRecord
+		if err := json.Unmarshal([]byte(ds.Data), &records); err != nil {
+			continue
+		}
+		events = append(events, records...)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	return events
+}
+
+func newWebhookCredentials() (string, string, error) {
+	token, err := randomHex(24)
+	if err != nil {
+		return "", "", err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", "", err
+	}
+	return token, secret, nil
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
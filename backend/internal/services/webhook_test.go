@@ -0,0 +1,133 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"myvault-backend/internal/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	const secret = "s3cret"
+	body := []byte(`{"type":"ci.build","title":"Build #1"}`)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	valid := hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name      string
+		signature string
+		body      []byte
+		wantErr   bool
+	}{
+		{"valid", "sha256=" + valid, body, false},
+		{"missing prefix", valid, body, true},
+		{"other algorithm", "sha1=" + valid, body, true},
+		{"uppercase prefix", "SHA256=" + valid, body, true},
+		{"empty", "", body, true},
+		{"prefix only", "sha256=", body, true},
+		{"not hex", "sha256=" + strings.Repeat("zz", 32), body, true},
+		{"truncated", "sha256=" + valid[:len(valid)-2], body, true},
+		{"wrong secret", "sha256=" + hex.EncodeToString(hmac.New(sha256.New, []byte("other")).Sum(nil)), body, true},
+		{"modified body", "sha256=" + valid, []byte(`{"type":"ci.build","title":"Build #2"}`), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyWebhookSignature(secret, tt.signature, tt.body)
+			if tt.wantErr && err != ErrInvalidSignature {
+				t.Errorf("error = %v, want ErrInvalidSignature", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}
+
+func TestNewWebhookRecord(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	webhook := &models.Webhook{ID: 7, Name: "ci"}
+
+	tests := []struct {
+		name    string
+		skew    time.Duration // 事件时间相对服务器时间的偏移
+		wantErr bool
+	}{
+		{"now", 0, false},
+		{"within past window", -webhookMaxSkew + time.Second, false},
+		{"within future window", webhookMaxSkew - time.Second, false},
+		{"replayed", -webhookMaxSkew - time.Second, true},
+		{"too far in the future", webhookMaxSkew + time.Second, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &models.WebhookEvent{ID: "build-1", Timestamp: now.Add(tt.skew), Type: "ci.build", Title: "Build #1"}
+			record, err := newWebhookRecord(webhook, event, now)
+			if tt.wantErr {
+				if err != ErrStaleEvent {
+					t.Errorf("error = %v, want ErrStaleEvent", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if record.ID != "7:build-1" || record.Hook != "ci" || !record.Time.Equal(event.Timestamp) {
+				t.Errorf("unexpected record %+v", record)
+			}
+		})
+	}
+}
+
+// 未提供id时按内容生成，相同的内容得到相同的id，与metadata的字段顺序无关
+func TestNewWebhookRecordContentID(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	webhook := &models.Webhook{ID: 7, Name: "ci"}
+
+	parse := func(body string) *models.WebhookEvent {
+		var event models.WebhookEvent
+		if err := json.Unmarshal([]byte(body), &event); err != nil {
+			t.Fatal(err)
+		}
+		return &event
+	}
+	id := func(event *models.WebhookEvent) string {
+		record, err := newWebhookRecord(webhook, event, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return record.ID
+	}
+
+	base := id(parse(`{"timestamp":"2024-05-01T12:00:00Z","type":"ci.build","title":"Build #1","metadata":{"branch":"main","status":"passed"}}`))
+	if !strings.HasPrefix(base, "7:") || len(base) != len("7:")+16 {
+		t.Fatalf("unexpected content id %q", base)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		same bool
+	}{
+		{"identical", `{"timestamp":"2024-05-01T12:00:00Z","type":"ci.build","title":"Build #1","metadata":{"branch":"main","status":"passed"}}`, true},
+		{"reordered fields", `{"metadata":{"status":"passed","branch":"main"},"title":"Build #1","type":"ci.build","timestamp":"2024-05-01T12:00:00Z"}`, true},
+		{"same instant in another zone", `{"timestamp":"2024-05-01T20:00:00+08:00","type":"ci.build","title":"Build #1","metadata":{"branch":"main","status":"passed"}}`, true},
+		{"different title", `{"timestamp":"2024-05-01T12:00:00Z","type":"ci.build","title":"Build #2","metadata":{"branch":"main","status":"passed"}}`, false},
+		{"different metadata", `{"timestamp":"2024-05-01T12:00:00Z","type":"ci.build","title":"Build #1","metadata":{"branch":"main","status":"failed"}}`, false},
+		{"different time", `{"timestamp":"2024-05-01T12:01:00Z","type":"ci.build","title":"Build #1","metadata":{"branch":"main","status":"passed"}}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := id(parse(tt.body)); (got == base) != tt.same {
+				t.Errorf("id = %q, base %q, want same %v", got, base, tt.same)
+			}
+		})
+	}
+}
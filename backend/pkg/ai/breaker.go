@@ -0,0 +1,95 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// CircuitBreaker 在Provider连续出现限流、5xx或网络错误达到阈值后熔断，冷却期内直接返回ErrCircuitOpen，
+// 避免模型服务故障时每天的同步都等待超时。冷却期结束后放行一次请求试探，成功则恢复
+type CircuitBreaker struct {
+	provider  Provider
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// threshold不大于0时不熔断
+func NewCircuitBreaker(provider Provider, threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		provider:  provider,
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+func (b *CircuitBreaker) Name() string {
+	return b.provider.Name()
+}
+
+func (b *CircuitBreaker) Model() string {
+	return b.provider.Model()
+}
+
+func (b *CircuitBreaker) GenerateSummary(ctx context.Context, req *SummaryRequest) (string, error) {
+	if err := b.allow(); err != nil {
+		return "", err
+	}
+
+	summary, err := b.provider.GenerateSummary(ctx, req)
+	b.record(err)
+	return summary, err
+}
+
+func (b *CircuitBreaker) allow() error {
+	if b.threshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return nil
+	}
+	// 熔断中，或冷却结束后已有请求在试探
+	if time.Now().Before(b.openUntil) || b.probing {
+		return ErrCircuitOpen
+	}
+	b.probing = true
+	return nil
+}
+
+func (b *CircuitBreaker) record(err error) {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wasProbing := b.probing
+	b.probing = false
+
+	switch {
+	case err == nil:
+		b.failures = 0
+	case errors.Is(err, context.Canceled):
+		// 调用方取消不代表服务故障
+	case retryable(err) || errors.Is(err, context.DeadlineExceeded):
+		b.failures++
+		if wasProbing || b.failures >= b.threshold {
+			b.failures = b.threshold
+			b.openUntil = time.Now().Add(b.cooldown)
+		}
+	default:
+		// 服务有响应（如请求参数错误），说明服务本身可用
+		b.failures = 0
+	}
+}
@@ -0,0 +1,143 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// 依次返回errs中的错误，用完后返回nil
+type scriptedProvider struct {
+	errs  []error
+	calls int
+}
+
+func (p *scriptedProvider) Name() string  { return "scripted" }
+func (p *scriptedProvider) Model() string { return "" }
+
+func (p *scriptedProvider) GenerateSummary(ctx context.Context, req *SummaryRequest) (string, error) {
+	p.calls++
+	if len(p.errs) == 0 {
+		return "ok", nil
+	}
+	err := p.errs[0]
+	p.errs = p.errs[1:]
+	return "", err
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	const cooldown = 50 * time.Millisecond
+	badRequest := &APIError{StatusCode: 400, Message: "bad request"}
+
+	type step struct {
+		wait      time.Duration // 调用前等待的时间
+		want      error         // 期望返回的错误，nil表示成功
+		wantCalls int           // 调用后Provider的累计调用次数
+	}
+
+	tests := []struct {
+		name      string
+		threshold int
+		errs      []error
+		steps     []step
+	}{
+		{
+			name:      "opens after consecutive failures",
+			threshold: 2,
+			errs:      []error{ErrUnavailable, ErrRateLimited},
+			steps: []step{
+				{want: ErrUnavailable, wantCalls: 1},
+				{want: ErrRateLimited, wantCalls: 2},
+				// 熔断中不调用Provider
+				{want: ErrCircuitOpen, wantCalls: 2},
+				{want: ErrCircuitOpen, wantCalls: 2},
+			},
+		},
+		{
+			name:      "closes after a successful probe",
+			threshold: 1,
+			errs:      []error{ErrUnavailable},
+			steps: []step{
+				{want: ErrUnavailable, wantCalls: 1},
+				{want: ErrCircuitOpen, wantCalls: 1},
+				{wait: 2 * cooldown, want: nil, wantCalls: 2},
+				{want: nil, wantCalls: 3},
+			},
+		},
+		{
+			name:      "reopens after a failed probe",
+			threshold: 2,
+			errs:      []error{ErrUnavailable, ErrUnavailable, context.DeadlineExceeded},
+			steps: []step{
+				{want: ErrUnavailable, wantCalls: 1},
+				{want: ErrUnavailable, wantCalls: 2},
+				{wait: 2 * cooldown, want: context.DeadlineExceeded, wantCalls: 3},
+				// 重新开始冷却
+				{want: ErrCircuitOpen, wantCalls: 3},
+				{wait: 2 * cooldown, want: nil, wantCalls: 4},
+			},
+		},
+		{
+			name:      "non-retryable errors and cancellation do not count",
+			threshold: 2,
+			errs:      []error{ErrUnavailable, badRequest, ErrUnavailable, context.Canceled, ErrUnavailable},
+			steps: []step{
+				{want: ErrUnavailable, wantCalls: 1},
+				// 服务有响应，计数清零
+				{want: badRequest, wantCalls: 2},
+				{want: ErrUnavailable, wantCalls: 3},
+				{want: context.Canceled, wantCalls: 4},
+				{want: ErrUnavailable, wantCalls: 5},
+				{want: ErrCircuitOpen, wantCalls: 5},
+			},
+		},
+		{
+			name:      "disabled without threshold",
+			threshold: 0,
+			errs:      []error{ErrUnavailable, ErrUnavailable, ErrUnavailable},
+			steps: []step{
+				{want: ErrUnavailable, wantCalls: 1},
+				{want: ErrUnavailable, wantCalls: 2},
+				{want: ErrUnavailable, wantCalls: 3},
+				{want: nil, wantCalls: 4},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &scriptedProvider{errs: tt.errs}
+			breaker := NewCircuitBreaker(provider, tt.threshold, cooldown)
+
+			for i, s := range tt.steps {
+				time.Sleep(s.wait)
+				_, err := breaker.GenerateSummary(context.Background(), &SummaryRequest{})
+				if !errors.Is(err, s.want) || (s.want == nil && err != nil) {
+					t.Fatalf("step %d: error = %v, want %v", i, err, s.want)
+				}
+				if provider.calls != s.wantCalls {
+					t.Fatalf("step %d: provider called %d times, want %d", i, provider.calls, s.wantCalls)
+				}
+			}
+		})
+	}
+}
+
+// 冷却结束后只放行一个试探请求，试探完成前其他请求仍然被拒绝
+func TestCircuitBreakerSingleProbe(t *testing.T) {
+	breaker := NewCircuitBreaker(&scriptedProvider{}, 1, 0)
+	breaker.record(ErrUnavailable)
+
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("probe rejected: %v", err)
+	}
+	if err := breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second request during probe: error = %v, want ErrCircuitOpen", err)
+	}
+
+	breaker.record(nil)
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("request after successful probe rejected: %v", err)
+	}
+}
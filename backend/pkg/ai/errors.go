@@ -0,0 +1,108 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"myvault-backend/pkg/httpapi"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnauthorized = errors.New("ai: unauthorized")
+	ErrRateLimited  = errors.New("ai: rate limit exceeded")
+	ErrUnavailable  = errors.New("ai: service unavailable")
+	ErrCircuitOpen  = errors.New("ai: circuit breaker open")
+)
+
+// APIError 表示模型服务返回的非2xx响应，可用errors.Is匹配上面的哨兵错误
+type APIError struct {
+	StatusCode int
+	Message    string
+	Type       string // OpenAI错误的type，如 insufficient_quota
+	Code       string
+	URL        string
+	// 服务端通过Retry-After要求的等待时间，没有时为0
+	RetryAfter time.Duration
+	kind       error
+}
+
+func (e *APIError) Error() string {
+	if e.Type != "" {
+		return fmt.Sprintf("ai: %s returned %d (%s): %s", e.URL, e.StatusCode, e.Type, e.Message)
+	}
+	return fmt.Sprintf("ai: %s returned %d: %s", e.URL, e.StatusCode, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.kind
+}
+
+// 解析错误响应，兼容OpenAI的 {"error": {"message", "type", "code"}} 和Ollama的 {"error": "..."}
+func newAPIError(resp *http.Response, endpoint string, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		URL:        endpoint,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+
+	var envelope struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err == nil && len(envelope.Error) > 0 {
+		var detail struct {
+			Message string          `json:"message"`
+			Type    string          `json:"type"`
+			Code    json.RawMessage `json:"code"`
+		}
+		var message string
+		if err := json.Unmarshal(envelope.Error, &detail); err == nil {
+			apiErr.Message = detail.Message
+			apiErr.Type = detail.Type
+			apiErr.Code = strings.Trim(string(detail.Code), `"`)
+			if apiErr.Code == "null" {
+				apiErr.Code = ""
+			}
+		} else if err := json.Unmarshal(envelope.Error, &message); err == nil {
+			apiErr.Message = message
+		}
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+	// 网关返回的错误页可能很长，只保留开头
+	apiErr.Message = httpapi.Truncate(apiErr.Message)
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		apiErr.kind = ErrUnauthorized
+	case resp.StatusCode == http.StatusTooManyRequests:
+		apiErr.kind = ErrRateLimited
+	case resp.StatusCode >= 500:
+		apiErr.kind = ErrUnavailable
+	}
+
+	return apiErr
+}
+
+// Retry-After可以是秒数或HTTP日期
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,100 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultOllamaBaseURL = "http://localhost:11434"
+	defaultOllamaModel   = "llama3"
+	// 本地模型首次加载和生成较慢
+	defaultOllamaTimeout = 5 * time.Minute
+)
+
+// OllamaClient 调用Ollama原生的 /api/chat 接口
+type OllamaClient struct {
+	baseURL    string
+	model      string
+	maxRetries int
+	httpClient *http.Client
+}
+
+type ollamaChatRequest struct {
+	Model    string        `json:"model"`
+	Messages []Message     `json:"messages"`
+	Stream   bool          `json:"stream"`
+	Options  ollamaOptions `json:"options"`
+}
+
+type ollamaOptions struct {
+	Temperature float64 `json:"temperature"`
+	NumPredict  int     `json:"num_predict"`
+}
+
+type ollamaChatResponse struct {
+	Model   string  `json:"model"`
+	Message Message `json:"message"`
+	Done    bool    `json:"done"`
+	Error   string  `json:"error"`
+}
+
+// BaseURL为Ollama服务地址，如 http://localhost:11434
+func NewOllamaClient(opts Options) *OllamaClient {
+	if opts.BaseURL == "" {
+		opts.BaseURL = defaultOllamaBaseURL
+	}
+	if opts.Model == "" {
+		opts.Model = defaultOllamaModel
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultOllamaTimeout
+	}
+
+	return &OllamaClient{
+		baseURL:    strings.TrimRight(opts.BaseURL, "/"),
+		model:      opts.Model,
+		maxRetries: opts.MaxRetries,
+		httpClient: &http.Client{Timeout: opts.Timeout},
+	}
+}
+
+func (c *OllamaClient) Name() string {
+	return ProviderOllama
+}
+
+func (c *OllamaClient) Model() string {
+	return c.model
+}
+
+func (c *OllamaClient) GenerateSummary(ctx context.Context, req *SummaryRequest) (string, error) {
+	request := ollamaChatRequest{
+		Model: c.model,
+		Messages: []Message{
+			{Role: "system", Content: req.systemPrompt()},
+			{Role: "user", Content: req.Prompt},
+		},
+		Stream:  false,
+		Options: ollamaOptions{Temperature: 0.7, NumPredict: 500},
+	}
+
+	var response ollamaChatResponse
+	err := withRetry(ctx, c.maxRetries, func() error {
+		return postJSON(ctx, c.httpClient, c.baseURL+"/api/chat", nil, request, &response)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if response.Error != "" {
+		return "", fmt.Errorf("ollama: %s", response.Error)
+	}
+	if response.Message.Content == "" {
+		return "", fmt.Errorf("no response from Ollama")
+	}
+
+	return response.Message.Content, nil
+}
@@ -0,0 +1,85 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOllamaGenerateSummary(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/chat" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		var req ollamaChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		if req.Model != defaultOllamaModel || req.Stream || req.Options.NumPredict != 500 {
+			t.Errorf("unexpected request %+v", req)
+		}
+		if len(req.Messages) != 2 || req.Messages[0].Content != "system" || req.Messages[1].Content != "prompt" {
+			t.Errorf("unexpected messages %+v", req.Messages)
+		}
+
+		fmt.Fprint(w, `{"model": "llama3", "message": {"role": "assistant", "content": "完成了搜索功能"}, "done": true}`)
+	}))
+	defer server.Close()
+
+	client := NewOllamaClient(Options{BaseURL: server.URL + "/"})
+	summary, err := client.GenerateSummary(context.Background(), &SummaryRequest{System: "system", Prompt: "prompt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary != "完成了搜索功能" {
+		t.Errorf("summary = %q", summary)
+	}
+}
+
+func TestOllamaErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"model not found", http.StatusNotFound, `{"error": "model 'llama3' not found, try pulling it first"}`},
+		{"error in body", http.StatusOK, `{"error": "out of memory"}`},
+		{"empty message", http.StatusOK, `{"message": {"role": "assistant", "content": ""}, "done": true}`},
+	}
+
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			fmt.Fprint(w, tt.body)
+		}))
+
+		client := NewOllamaClient(Options{BaseURL: server.URL})
+		_, err := client.GenerateSummary(context.Background(), &SummaryRequest{})
+		server.Close()
+		if err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+// Ollama的错误响应为 {"error": "..."}
+func TestOllamaAPIErrorMessage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error": "model not found"}`)
+	}))
+	defer server.Close()
+
+	client := NewOllamaClient(Options{BaseURL: server.URL})
+	_, err := client.GenerateSummary(context.Background(), &SummaryRequest{})
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "model not found" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
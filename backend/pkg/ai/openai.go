@@ -2,23 +2,35 @@ package ai
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
+	"myvault-backend/pkg/httpapi"
 	"net/http"
+	"strings"
 	"time"
 )
 
+const (
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+	defaultOpenAIModel   = "gpt-3.5-turbo"
+	defaultOpenAITimeout = 60 * time.Second
+)
+
+// OpenAIClient 调用兼容OpenAI的chat completions接口
 type OpenAIClient struct {
+	baseURL    string
 	apiKey     string
+	model      string
+	maxRetries int
 	httpClient *http.Client
 }
 
 type ChatRequest struct {
-	Model    string    `json:"model"`
-	Messages []Message `json:"messages"`
-	MaxTokens int      `json:"max_tokens"`
-	Temperature float64 `json:"temperature"`
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens"`
+	Temperature float64   `json:"temperature"`
 }
 
 type Message struct {
@@ -36,9 +48,9 @@ type ChatResponse struct {
 }
 
 type Choice struct {
-	Index   int     `json:"index"`
-	Message Message `json:"message"`
-	FinishReason string `json:"finish_reason"`
+	Index        int     `json:"index"`
+	Message      Message `json:"message"`
+	FinishReason string  `json:"finish_reason"`
 }
 
 type Usage struct {
@@ -47,66 +59,104 @@ type Usage struct {
 	TotalTokens      int `json:"total_tokens"`
 }
 
-func NewOpenAIClient(apiKey string) *OpenAIClient {
+// BaseURL为API根地址，如 https://api.openai.com/v1、http://localhost:11434/v1；
+// 本地服务通常不需要APIKey，为空时不发送Authorization头
+func NewOpenAIClient(opts Options) *OpenAIClient {
+	if opts.BaseURL == "" {
+		opts.BaseURL = defaultOpenAIBaseURL
+	}
+	if opts.Model == "" {
+		opts.Model = defaultOpenAIModel
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultOpenAITimeout
+	}
+
 	return &OpenAIClient{
-		apiKey:     apiKey,
-		httpClient: &http.Client{Timeout: 60 * time.Second},
+		baseURL:    strings.TrimRight(opts.BaseURL, "/"),
+		apiKey:     opts.APIKey,
+		model:      opts.Model,
+		maxRetries: opts.MaxRetries,
+		httpClient: &http.Client{Timeout: opts.Timeout},
 	}
 }
 
-func (c *OpenAIClient) GenerateSummary(prompt string) (string, error) {
-	if c.apiKey == "" {
-		return "AI服务未配置，无法生成摘要", nil
-	}
+func (c *OpenAIClient) Name() string {
+	return ProviderOpenAI
+}
+
+func (c *OpenAIClient) Model() string {
+	return c.model
+}
 
+func (c *OpenAIClient) GenerateSummary(ctx context.Context, req *SummaryRequest) (string, error) {
 	request := ChatRequest{
-		Model: "gpt-3.5-turbo",
+		Model: c.model,
 		Messages: []Message{
 			{
 				Role:    "system",
-				Content: "你是一个专业的程序员活动总结助手。请根据提供的代码提交记录，生成一份简洁明了的每日编程活动摘要。摘要应该包含主要完成的功能、修复的问题、以及总体的工作重点。请使用中文回答，语调要专业但不失亲和力。",
+				Content: req.systemPrompt(),
 			},
 			{
 				Role:    "user",
-				Content: prompt,
+				Content: req.Prompt,
 			},
 		},
 		MaxTokens:   500,
 		Temperature: 0.7,
 	}
 
-	jsonData, err := json.Marshal(request)
+	headers := map[string]string{}
+	if c.apiKey != "" {
+		headers["Authorization"] = "Bearer " + c.apiKey
+	}
+
+	var response ChatResponse
+	err := withRetry(ctx, c.maxRetries, func() error {
+		return postJSON(ctx, c.httpClient, c.baseURL+"/chat/completions", headers, request, &response)
+	})
 	if err != nil {
 		return "", err
 	}
 
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonData))
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("no response from OpenAI")
+	}
+
+	return response.Choices[0].Message.Content, nil
+}
+
+// 发送JSON请求并解析响应，非2xx响应返回*APIError
+func postJSON(ctx context.Context, httpClient *http.Client, endpoint string, headers map[string]string, body, v interface{}) error {
+	jsonData, err := json.Marshal(body)
 	if err != nil {
-		return "", err
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
-		return "", err
+		return err
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	respBody, err := httpapi.ReadBody(resp.Body)
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	var response ChatResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return "", err
-	}
-
-	if len(response.Choices) == 0 {
-		return "", fmt.Errorf("no response from OpenAI")
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return newAPIError(resp, endpoint, respBody)
 	}
 
-	return response.Choices[0].Message.Content, nil
-}
\ No newline at end of file
+	return json.Unmarshal(respBody, v)
+}
@@ -0,0 +1,168 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOpenAIGenerateSummary(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-test" {
+			t.Errorf("Authorization = %q", got)
+		}
+
+		var req ChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		if req.Model != "test-model" || len(req.Messages) != 2 {
+			t.Errorf("unexpected request %+v", req)
+		}
+		if req.Messages[0].Role != "system" || req.Messages[0].Content != DefaultSystemPrompt {
+			t.Errorf("unexpected system message %+v", req.Messages[0])
+		}
+		if req.Messages[1].Role != "user" || req.Messages[1].Content != "prompt" {
+			t.Errorf("unexpected user message %+v", req.Messages[1])
+		}
+
+		fmt.Fprint(w, `{"id": "chatcmpl-1", "model": "test-model", "choices": [{"index": 0, "message": {"role": "assistant", "content": "今天修复了登录问题"}}]}`)
+	}))
+	defer server.Close()
+
+	client := NewOpenAIClient(Options{BaseURL: server.URL + "/v1/", APIKey: "sk-test", Model: "test-model"})
+	summary, err := client.GenerateSummary(context.Background(), &SummaryRequest{Prompt: "prompt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary != "今天修复了登录问题" {
+		t.Errorf("summary = %q", summary)
+	}
+}
+
+// 本地服务不配置APIKey时不发送Authorization头
+func TestOpenAIWithoutAPIKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "" {
+			t.Errorf("unexpected Authorization %q", got)
+		}
+		fmt.Fprint(w, `{"choices": [{"message": {"content": "ok"}}]}`)
+	}))
+	defer server.Close()
+
+	client := NewOpenAIClient(Options{BaseURL: server.URL})
+	if _, err := client.GenerateSummary(context.Background(), &SummaryRequest{System: "custom"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenAIEmptyChoices(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"choices": []}`)
+	}))
+	defer server.Close()
+
+	client := NewOpenAIClient(Options{BaseURL: server.URL})
+	if _, err := client.GenerateSummary(context.Background(), &SummaryRequest{}); err == nil {
+		t.Fatal("expected error for empty choices")
+	}
+}
+
+func TestOpenAIErrors(t *testing.T) {
+	tests := []struct {
+		status    int
+		body      string
+		want      error
+		message   string
+		errorType string
+		code      string
+	}{
+		{http.StatusUnauthorized, `{"error": {"message": "Incorrect API key", "type": "invalid_request_error", "code": "invalid_api_key"}}`,
+			ErrUnauthorized, "Incorrect API key", "invalid_request_error", "invalid_api_key"},
+		{http.StatusTooManyRequests, `{"error": {"message": "quota", "type": "insufficient_quota", "code": null}}`,
+			ErrRateLimited, "quota", "insufficient_quota", ""},
+		{http.StatusServiceUnavailable, `upstream down`, ErrUnavailable, "upstream down", "", ""},
+		{http.StatusBadRequest, ``, nil, "Bad Request", "", ""},
+		// 网关的错误页只保留开头
+		{http.StatusBadGateway, strings.Repeat("x", 1000), ErrUnavailable, strings.Repeat("x", 200) + "...(truncated)", "", ""},
+	}
+
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			fmt.Fprint(w, tt.body)
+		}))
+
+		// 不重试，只检查错误解析
+		client := NewOpenAIClient(Options{BaseURL: server.URL})
+		_, err := client.GenerateSummary(context.Background(), &SummaryRequest{})
+		server.Close()
+
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("status %d: expected APIError, got %v", tt.status, err)
+		}
+		if apiErr.Message != tt.message || apiErr.Type != tt.errorType || apiErr.Code != tt.code {
+			t.Errorf("status %d: unexpected error %+v", tt.status, apiErr)
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("status %d: expected %v, got %v", tt.status, tt.want, err)
+		}
+	}
+}
+
+// 限流时按Retry-After等待后重试
+func TestOpenAIRetriesRateLimit(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"error": {"message": "slow down"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"choices": [{"message": {"content": "ok"}}]}`)
+	}))
+	defer server.Close()
+
+	client := NewOpenAIClient(Options{BaseURL: server.URL, MaxRetries: 1})
+	start := time.Now()
+	summary, err := client.GenerateSummary(context.Background(), &SummaryRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary != "ok" || atomic.LoadInt32(&calls) != 2 {
+		t.Errorf("summary = %q after %d calls", summary, calls)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, want at least Retry-After", elapsed)
+	}
+}
+
+// 认证失败不重试
+func TestOpenAIDoesNotRetryUnauthorized(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client := NewOpenAIClient(Options{BaseURL: server.URL, MaxRetries: 3})
+	if _, err := client.GenerateSummary(context.Background(), &SummaryRequest{}); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("got %d calls, want 1", calls)
+	}
+}
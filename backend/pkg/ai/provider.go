@@ -0,0 +1,70 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 默认的系统提示词，用于每日活动摘要
+const DefaultSystemPrompt = "你是一个专业的程序员活动总结助手。请根据提供的代码提交记录，生成一份简洁明了的每日编程活动摘要。摘要应该包含主要完成的功能、修复的问题、以及总体的工作重点。请使用中文回答，语调要专业但不失亲和力。"
+
+const (
+	ProviderOpenAI   = "openai"
+	ProviderOllama   = "ollama"
+	ProviderTemplate = "template"
+)
+
+// Provider 是生成摘要的后端，可以是远程或本地的大模型，也可以是不依赖模型的模板
+type Provider interface {
+	Name() string
+	// Model 返回实际使用的模型，模板实现返回空字符串
+	Model() string
+	GenerateSummary(ctx context.Context, req *SummaryRequest) (string, error)
+}
+
+// SummaryRequest 是生成摘要的输入。大模型使用System和Prompt；
+// 模板实现只使用Title、Stats和Highlights，由调用方从同样的数据整理
+type SummaryRequest struct {
+	System     string // 为空时使用DefaultSystemPrompt
+	Prompt     string
+	Title      string   // 如"今日"
+	Stats      []string // 如"5次提交"
+	Highlights []string // 如提交信息、PR标题
+}
+
+func (r *SummaryRequest) systemPrompt() string {
+	if r.System != "" {
+		return r.System
+	}
+	return DefaultSystemPrompt
+}
+
+type Options struct {
+	BaseURL string // 为空时使用各实现的默认地址
+	APIKey  string
+	Model   string // 为空时使用各实现的默认模型
+	// 单次请求的超时，为0时使用各实现的默认值
+	Timeout time.Duration
+	// 限流、5xx和网络错误的最大重试次数
+	MaxRetries int
+}
+
+// NewProvider 根据名称创建Provider。openai适用于所有兼容OpenAI接口的服务（vLLM、LM Studio、Ollama的OpenAI模式等），
+// 未配置API Key且使用官方地址时退回模板实现
+func NewProvider(name string, opts Options) (Provider, error) {
+	switch strings.ToLower(name) {
+	case "", ProviderOpenAI:
+		if opts.APIKey == "" && opts.BaseURL == "" {
+			return NewTemplateProvider(), nil
+		}
+		return NewOpenAIClient(opts), nil
+	case ProviderOllama:
+		return NewOllamaClient(opts), nil
+	case ProviderTemplate:
+		return NewTemplateProvider(), nil
+	default:
+		return nil, fmt.Errorf("ai: unknown provider %q", name)
+	}
+}
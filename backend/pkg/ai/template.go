@@ -0,0 +1,62 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"text/template"
+)
+
+// 摘要中最多列出的要点数
+const maxHighlights = 10
+
+var summaryTemplate = template.Must(template.New("summary").Funcs(template.FuncMap{
+	"join": strings.Join,
+}).Parse(`{{.Title}}{{if .Stats}}共有{{join .Stats "、"}}{{else}}没有统计数据{{end}}。
+{{- if .Highlights}}
+
+主要内容：
+{{- range .Highlights}}
+- {{.}}
+{{- end}}
+{{- if .More}}
+- 以及其他{{.More}}项
+{{- end}}
+{{- end}}`))
+
+// TemplateProvider 不调用大模型，用固定模板排版统计和要点。
+// 结果只取决于输入，适合未配置模型或离线的环境
+type TemplateProvider struct{}
+
+func NewTemplateProvider() *TemplateProvider {
+	return &TemplateProvider{}
+}
+
+func (p *TemplateProvider) Name() string {
+	return ProviderTemplate
+}
+
+func (p *TemplateProvider) Model() string {
+	return ""
+}
+
+func (p *TemplateProvider) GenerateSummary(ctx context.Context, req *SummaryRequest) (string, error) {
+	highlights := req.Highlights
+	more := 0
+	if len(highlights) > maxHighlights {
+		more = len(highlights) - maxHighlights
+		highlights = highlights[:maxHighlights]
+	}
+
+	var builder strings.Builder
+	err := summaryTemplate.Execute(&builder, struct {
+		Title      string
+		Stats      []string
+		Highlights []string
+		More       int
+	}{req.Title, req.Stats, highlights, more})
+	if err != nil {
+		return "", err
+	}
+
+	return builder.String(), nil
+}
@@ -0,0 +1,97 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTemplateGenerateSummary(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *SummaryRequest
+		want string
+	}{
+		{
+			name: "stats and highlights",
+			req: &SummaryRequest{
+				Title:      "今日",
+				Stats:      []string{"5次提交", "1次代码评审"},
+				Highlights: []string{"修复登录问题", "添加搜索"},
+			},
+			want: "今日共有5次提交、1次代码评审。\n\n主要内容：\n- 修复登录问题\n- 添加搜索",
+		},
+		{
+			name: "no stats",
+			req:  &SummaryRequest{Title: "本周"},
+			want: "本周没有统计数据。",
+		},
+	}
+
+	provider := NewTemplateProvider()
+	for _, tt := range tests {
+		got, err := provider.GenerateSummary(context.Background(), tt.req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTemplateLimitsHighlights(t *testing.T) {
+	var highlights []string
+	for i := 1; i <= maxHighlights+3; i++ {
+		highlights = append(highlights, fmt.Sprintf("提交%d", i))
+	}
+
+	got, err := NewTemplateProvider().GenerateSummary(context.Background(), &SummaryRequest{
+		Title:      "今日",
+		Stats:      []string{"13次提交"},
+		Highlights: highlights,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(got, "\n- "); n != maxHighlights+1 {
+		t.Errorf("got %d list items, want %d", n, maxHighlights+1)
+	}
+	if !strings.HasSuffix(got, "- 以及其他3项") {
+		t.Errorf("missing remainder line in %q", got)
+	}
+	if strings.Contains(got, fmt.Sprintf("提交%d", maxHighlights+1)) {
+		t.Errorf("highlight beyond the limit in %q", got)
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      Options
+		want      string
+		wantModel string
+		wantErr   bool
+	}{
+		{"", Options{}, ProviderTemplate, "", false},
+		{"openai", Options{APIKey: "sk-test"}, ProviderOpenAI, defaultOpenAIModel, false},
+		{"OpenAI", Options{BaseURL: "http://localhost:8000/v1", Model: "qwen2"}, ProviderOpenAI, "qwen2", false},
+		{"ollama", Options{}, ProviderOllama, defaultOllamaModel, false},
+		{"template", Options{APIKey: "sk-test", Model: "gpt-4o"}, ProviderTemplate, "", false},
+		{"claude", Options{}, "", "", true},
+	}
+
+	for _, tt := range tests {
+		provider, err := NewProvider(tt.name, tt.opts)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("NewProvider(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if err == nil && provider.Name() != tt.want {
+			t.Errorf("NewProvider(%q) = %s, want %s", tt.name, provider.Name(), tt.want)
+		}
+		if err == nil && provider.Model() != tt.wantModel {
+			t.Errorf("NewProvider(%q).Model() = %q, want %q", tt.name, provider.Model(), tt.wantModel)
+		}
+	}
+}
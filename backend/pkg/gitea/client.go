@@ -0,0 +1,152 @@
+package gitea
+
+import (
+	"errors"
+	"fmt"
+	"myvault-backend/pkg/httpapi"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound     = errors.New("gitea: not found")
+	ErrUnauthorized = errors.New("gitea: unauthorized")
+	// 令牌有效但没有权限访问该资源，如被移出组织的仓库
+	ErrForbidden   = errors.New("gitea: forbidden")
+	ErrRateLimited = errors.New("gitea: rate limit exceeded")
+)
+
+// 每页条数，Gitea默认的最大值为50
+const pageSize = 50
+
+// APIError 表示Gitea返回的非2xx响应，可用errors.Is匹配上面的哨兵错误
+type APIError = httpapi.Error
+
+// Client 同样适用于Forgejo，两者的API相同
+type Client struct {
+	baseURL string
+	api     *httpapi.Client
+}
+
+type User struct {
+	ID        int    `json:"id"`
+	Login     string `json:"login"`
+	FullName  string `json:"full_name"`
+	Email     string `json:"email"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+type Repository struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	FullName    string `json:"full_name"`
+	Description string `json:"description"`
+	Language    string `json:"language"`
+	Private     bool   `json:"private"`
+	Fork        bool   `json:"fork"`
+	Archived    bool   `json:"archived"`
+	Empty       bool   `json:"empty"`
+}
+
+type Commit struct {
+	SHA     string `json:"sha"`
+	HTMLURL string `json:"html_url"`
+	Commit  struct {
+		Message string `json:"message"`
+		Author  struct {
+			Name  string    `json:"name"`
+			Email string    `json:"email"`
+			Date  time.Time `json:"date"`
+		} `json:"author"`
+	} `json:"commit"`
+	Author *struct {
+		Login string `json:"login"`
+	} `json:"author"`
+	Stats struct {
+		Additions int `json:"additions"`
+		Deletions int `json:"deletions"`
+		Total     int `json:"total"`
+	} `json:"stats"`
+	Files []struct {
+		Filename string `json:"filename"`
+	} `json:"files"`
+	Repository string `json:"-"` // 由GetRepositoryCommits填充
+}
+
+// baseURL为实例地址，如 https://gitea.example.com
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		api: &httpapi.Client{
+			Service:    "gitea",
+			HTTPClient: &http.Client{Timeout: 30 * time.Second},
+			Classify: httpapi.Kinds{
+				Unauthorized: ErrUnauthorized,
+				Forbidden:    ErrForbidden,
+				NotFound:     ErrNotFound,
+				RateLimited:  ErrRateLimited,
+			}.Classify,
+			MessageFields: []string{"message"},
+		},
+	}
+}
+
+// SetHTTPClient 替换发送请求的客户端，访问用户填写的实例地址时使用safehttp
+func (c *Client) SetHTTPClient(httpClient *http.Client) {
+	c.api.HTTPClient = httpClient
+}
+
+func (c *Client) GetUser(accessToken string) (*User, error) {
+	var user User
+	if _, err := c.get(accessToken, c.baseURL+"/api/v1/user", &user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// 获取用户有权限访问的所有仓库，包括组织仓库
+func (c *Client) GetUserRepositories(accessToken string) ([]Repository, error) {
+	endpoint := fmt.Sprintf("%s/api/v1/user/repos?limit=%d", c.baseURL, pageSize)
+	return httpapi.GetAll[Repository](endpoint, c.getter(accessToken), httpapi.NextLink)
+}
+
+// 获取仓库默认分支上since之后的提交，按时间倒序返回。
+// Gitea不支持按作者过滤，由调用方筛选
+func (c *Client) GetRepositoryCommits(accessToken, repo string, since time.Time) ([]Commit, error) {
+	params := url.Values{}
+	params.Set("since", since.Format(time.RFC3339))
+	params.Set("stat", "true")
+	params.Set("files", "true")
+	params.Set("limit", fmt.Sprint(pageSize))
+	endpoint := fmt.Sprintf("%s/api/v1/repos/%s/commits?%s", c.baseURL, repo, params.Encode())
+
+	all, err := httpapi.GetAll[Commit](endpoint, c.getter(accessToken), httpapi.NextLink)
+	if err != nil {
+		// 空仓库返回409
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	for i := range all {
+		all[i].Repository = repo
+	}
+
+	return all, nil
+}
+
+func (c *Client) get(accessToken, endpoint string, v interface{}) (http.Header, error) {
+	return c.api.Get(endpoint, "token "+accessToken, v)
+}
+
+// 用于httpapi.GetAll逐页请求
+func (c *Client) getter(accessToken string) func(endpoint string, v interface{}) (http.Header, error) {
+	return func(endpoint string, v interface{}) (http.Header, error) {
+		return c.get(accessToken, endpoint, v)
+	}
+}
@@ -0,0 +1,160 @@
+package gitea
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewClient(server.URL)
+}
+
+func TestGetUser(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/user" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "token secret" {
+			t.Errorf("Authorization = %q", got)
+		}
+		fmt.Fprint(w, `{"id": 3, "login": "bob", "full_name": "Bob", "email": "bob@example.com"}`)
+	})
+
+	user, err := client.GetUser("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != 3 || user.Login != "bob" || user.Email != "bob@example.com" {
+		t.Errorf("unexpected user %+v", user)
+	}
+}
+
+func TestGetUserRepositoriesPagination(t *testing.T) {
+	var serverURL string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("limit") != fmt.Sprint(pageSize) {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/api/v1/user/repos?limit=%d&page=2>; rel="next",<%s/api/v1/user/repos?limit=%d&page=2>; rel="last"`,
+				serverURL, pageSize, serverURL, pageSize))
+			fmt.Fprint(w, `[{"id": 1, "full_name": "bob/one", "private": true}]`)
+		case "2":
+			fmt.Fprint(w, `[{"id": 2, "full_name": "bob/two", "fork": true, "empty": true}]`)
+		default:
+			t.Errorf("unexpected page %q", r.URL.Query().Get("page"))
+		}
+	})
+	serverURL = client.baseURL
+
+	repos, err := client.GetUserRepositories("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 2 {
+		t.Fatalf("got %d repositories, want 2", len(repos))
+	}
+	if !repos[0].Private || !repos[1].Fork || !repos[1].Empty {
+		t.Errorf("unexpected repositories %+v", repos)
+	}
+}
+
+func TestGetRepositoryCommits(t *testing.T) {
+	since := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/repos/bob/app/commits" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		query := r.URL.Query()
+		if query.Get("since") != "2024-05-01T00:00:00Z" || query.Get("stat") != "true" || query.Get("files") != "true" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		fmt.Fprint(w, `[{
+			"sha": "def456",
+			"commit": {
+				"message": "Add search",
+				"author": {"name": "Bob", "email": "bob@example.com", "date": "2024-05-03T09:00:00Z"}
+			},
+			"author": {"login": "bob"},
+			"stats": {"additions": 40, "deletions": 2, "total": 42},
+			"files": [{"filename": "search.go"}, {"filename": "search_test.go"}]
+		}, {
+			"sha": "789abc",
+			"commit": {
+				"message": "Import",
+				"author": {"name": "Someone", "email": "someone@example.com", "date": "2024-05-02T09:00:00Z"}
+			},
+			"author": null
+		}]`)
+	})
+
+	commits, err := client.GetRepositoryCommits("secret", "bob/app", since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("got %d commits, want 2", len(commits))
+	}
+	first := commits[0]
+	if first.SHA != "def456" || first.Repository != "bob/app" || first.Author == nil || first.Author.Login != "bob" ||
+		first.Stats.Additions != 40 || len(first.Files) != 2 {
+		t.Errorf("unexpected commit %+v", first)
+	}
+	if commits[1].Author != nil {
+		t.Errorf("expected commit without linked account, got %+v", commits[1].Author)
+	}
+}
+
+// 空仓库返回409，视为没有提交
+func TestGetRepositoryCommitsEmptyRepository(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprint(w, `{"message": "Git Repository is empty."}`)
+	})
+
+	commits, err := client.GetRepositoryCommits("secret", "bob/empty", time.Now())
+	if err != nil || commits != nil {
+		t.Fatalf("expected no commits and no error, got %v, %v", commits, err)
+	}
+}
+
+// 状态码分类和错误信息截断由httpapi测试，这里只检查Gitea的映射和错误字段
+func TestAPIErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusUnauthorized, ErrUnauthorized},
+		{http.StatusForbidden, ErrForbidden},
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusTooManyRequests, ErrRateLimited},
+	}
+
+	for _, tt := range tests {
+		client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			fmt.Fprint(w, `{"message": "denied"}`)
+		})
+
+		_, err := client.GetUser("secret")
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || !errors.Is(err, tt.want) {
+			t.Fatalf("status %d: expected %v, got %v", tt.status, tt.want, err)
+		}
+		// 403只影响单个仓库，不能被当作令牌失效而中止同步
+		if errors.Is(err, ErrUnauthorized) && tt.want != ErrUnauthorized {
+			t.Errorf("status %d: should not be treated as unauthorized", tt.status)
+		}
+		if apiErr.Message != "denied" {
+			t.Errorf("status %d: Message = %q", tt.status, apiErr.Message)
+		}
+	}
+}
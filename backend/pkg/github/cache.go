@@ -0,0 +1,64 @@
+package github
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 条件请求缓存的有效期
+const cacheTTL = 7 * 24 * time.Hour
+
+// CachedResponse 保存一次成功响应，用于条件请求返回304时复用
+type CachedResponse struct {
+	ETag         string `json:"etag"`
+	LastModified string `json:"last_modified"`
+	Link         string `json:"link"`
+	Body         []byte `json:"body"`
+}
+
+type Cache interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, resp *CachedResponse)
+}
+
+type RedisCache struct {
+	client *redis.Client
+}
+
+func NewRedisCache(client *redis.Client) *RedisCache {
+	return &RedisCache{client: client}
+}
+
+func (c *RedisCache) Get(key string) (*CachedResponse, bool) {
+	data, err := c.client.Get(context.Background(), "myvault:github:etag:"+key).Bytes()
+	if err != nil {
+		return nil, false
+	}
+
+	var resp CachedResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, false
+	}
+
+	return &resp, true
+}
+
+// 缓存写入失败只会导致下次请求不带条件头，忽略错误
+func (c *RedisCache) Set(key string, resp *CachedResponse) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	c.client.Set(context.Background(), "myvault:github:etag:"+key, data, cacheTTL)
+}
+
+// 不同令牌可见的数据不同，缓存键需要同时包含令牌和URL
+func cacheKey(accessToken, url string) string {
+	sum := sha256.Sum256([]byte(accessToken + "\n" + url))
+	return hex.EncodeToString(sum[:])
+}
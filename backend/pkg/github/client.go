@@ -2,11 +2,14 @@ package github
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"myvault-backend/pkg/httpapi"
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -14,6 +17,11 @@ type Client struct {
 	clientID     string
 	clientSecret string
 	httpClient   *http.Client
+
+	mu         sync.Mutex
+	rateLimits map[string]rateLimit // 按访问令牌记录的限流状态
+
+	cache Cache // 可为nil，为nil时不发送条件请求
 }
 
 type User struct {
@@ -25,8 +33,9 @@ type User struct {
 }
 
 type Commit struct {
-	SHA    string `json:"sha"`
-	Commit struct {
+	SHA        string `json:"sha"`
+	Repository string `json:"-"` // 由GetRepositoryCommits填充，列表接口不返回仓库信息
+	Commit     struct {
 		Author struct {
 			Name  string    `json:"name"`
 			Email string    `json:"email"`
@@ -54,6 +63,15 @@ type Repository struct {
 	Description string `json:"description"`
 	Language    string `json:"language"`
 	Private     bool   `json:"private"`
+	Fork        bool   `json:"fork"`
+	Archived    bool   `json:"archived"`
+}
+
+type Branch struct {
+	Name   string `json:"name"`
+	Commit struct {
+		SHA string `json:"sha"`
+	} `json:"commit"`
 }
 
 func NewClient(clientID, clientSecret string) *Client {
@@ -61,9 +79,15 @@ func NewClient(clientID, clientSecret string) *Client {
 		clientID:     clientID,
 		clientSecret: clientSecret,
 		httpClient:   &http.Client{Timeout: 30 * time.Second},
+		rateLimits:   make(map[string]rateLimit),
 	}
 }
 
+// SetCache 启用基于ETag/Last-Modified的条件请求，304响应不计入GitHub配额
+func (c *Client) SetCache(cache Cache) {
+	c.cache = cache
+}
+
 func (c *Client) GetAccessToken(code string) (string, error) {
 	data := url.Values{}
 	data.Set("client_id", c.clientID)
@@ -84,7 +108,7 @@ func (c *Client) GetAccessToken(code string) (string, error) {
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := httpapi.ReadBody(resp.Body)
 	if err != nil {
 		return "", err
 	}
@@ -102,27 +126,8 @@ func (c *Client) GetAccessToken(code string) (string, error) {
 }
 
 func (c *Client) GetUser(accessToken string) (*User, error) {
-	req, err := http.NewRequest("GET", "https://api.github.com/user", nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Authorization", "token "+accessToken)
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
 	var user User
-	if err := json.Unmarshal(body, &user); err != nil {
+	if _, err := c.get(accessToken, "https://api.github.com/user", &user); err != nil {
 		return nil, err
 	}
 
@@ -131,57 +136,181 @@ func (c *Client) GetUser(accessToken string) (*User, error) {
 
 func (c *Client) GetUserCommits(accessToken, username string, since time.Time) ([]Commit, error) {
 	// 首先获取用户的仓库
-	repos, err := c.GetUserRepositories(accessToken, username)
+	repos, err := c.GetUserRepositories(accessToken)
 	if err != nil {
 		return nil, err
 	}
 
+	// 单个仓库失败时继续处理其他仓库，限流或授权失败时立即停止
 	var allCommits []Commit
+	var errs []error
 	for _, repo := range repos {
 		commits, err := c.GetRepositoryCommits(accessToken, repo.FullName, username, since)
 		if err != nil {
-			continue // 忽略错误，继续处理其他仓库
+			if errors.Is(err, ErrRateLimited) || errors.Is(err, ErrUnauthorized) {
+				return allCommits, err
+			}
+			errs = append(errs, err)
+			continue
 		}
 		allCommits = append(allCommits, commits...)
 	}
 
-	return allCommits, nil
+	return allCommits, errors.Join(errs...)
 }
 
-func (c *Client) GetUserRepositories(accessToken, username string) ([]Repository, error) {
-	url := fmt.Sprintf("https://api.github.com/users/%s/repos?type=owner&sort=updated&per_page=100", username)
-	
-	req, err := http.NewRequest("GET", url, nil)
+// 获取令牌对应用户可访问的仓库，包括自己的、作为协作者的以及所在组织的仓库
+func (c *Client) GetUserRepositories(accessToken string) ([]Repository, error) {
+	url := "https://api.github.com/user/repos?affiliation=owner,collaborator,organization_member&sort=updated&per_page=100"
+
+	return httpapi.GetAll[Repository](url, c.getter(accessToken), httpapi.NextLink)
+}
+
+func (c *Client) GetRepositoryBranches(accessToken, repoFullName string) ([]Branch, error) {
+	url := fmt.Sprintf("https://api.github.com/repos/%s/branches?per_page=100", repoFullName)
+
+	return httpapi.GetAll[Branch](url, c.getter(accessToken), httpapi.NextLink)
+}
+
+// 只获取默认分支上的提交
+func (c *Client) GetRepositoryCommits(accessToken, repoFullName, author string, since time.Time) ([]Commit, error) {
+	return c.GetBranchCommits(accessToken, repoFullName, "", author, since)
+}
+
+// 获取所有分支上的提交，按SHA去重并按时间倒序排列
+func (c *Client) GetAllBranchCommits(accessToken, repoFullName, author string, since time.Time) ([]Commit, error) {
+	branches, err := c.GetRepositoryBranches(accessToken, repoFullName)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("Authorization", "token "+accessToken)
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	seen := make(map[string]bool)
+	var all []Commit
+	for _, branch := range branches {
+		commits, err := c.GetBranchCommits(accessToken, repoFullName, branch.Name, author, since)
+		if err != nil {
+			return nil, err
+		}
+		for _, commit := range commits {
+			if !seen[commit.SHA] {
+				seen[commit.SHA] = true
+				all = append(all, commit)
+			}
+		}
+	}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].Commit.Author.Date.After(all[j].Commit.Author.Date)
+	})
+
+	return all, nil
+}
+
+// branch为空时使用仓库默认分支
+func (c *Client) GetBranchCommits(accessToken, repoFullName, branch, author string, since time.Time) ([]Commit, error) {
+	endpoint := fmt.Sprintf("https://api.github.com/repos/%s/commits?author=%s&since=%s&per_page=100",
+		repoFullName, url.QueryEscape(author), url.QueryEscape(since.Format(time.RFC3339)))
+	if branch != "" {
+		endpoint += "&sha=" + url.QueryEscape(branch)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	commits, err := httpapi.GetAll[Commit](endpoint, c.getter(accessToken), httpapi.NextLink)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict {
+		// 空仓库返回409
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	var repos []Repository
-	if err := json.Unmarshal(body, &repos); err != nil {
+	for i := range commits {
+		commits[i].Repository = repoFullName
+	}
+
+	return commits, nil
+}
+
+// 列表接口不返回stats和files，需要逐个获取提交详情
+func (c *Client) GetCommit(accessToken, repoFullName, sha string) (*Commit, error) {
+	url := fmt.Sprintf("https://api.github.com/repos/%s/commits/%s", repoFullName, sha)
+
+	var commit Commit
+	if _, err := c.get(accessToken, url, &commit); err != nil {
 		return nil, err
 	}
+	commit.Repository = repoFullName
 
-	return repos, nil
+	return &commit, nil
 }
 
-func (c *Client) GetRepositoryCommits(accessToken, repoFullName, author string, since time.Time) ([]Commit, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/commits?author=%s&since=%s&per_page=100", 
-		repoFullName, author, since.Format(time.RFC3339))
-	
+// FetchCommitDetails 按SHA和Repository并发补全commits中的提交信息、Stats和Files，
+// concurrency限制同时进行的请求数。单个提交失败不影响其他提交，返回遇到的第一个错误
+func (c *Client) FetchCommitDetails(accessToken string, commits []Commit, concurrency int) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	sem := make(chan struct{}, concurrency)
+
+	for i := range commits {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(commit *Commit) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			detail, err := c.GetCommit(accessToken, commit.Repository, commit.SHA)
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				return
+			}
+			commit.Commit = detail.Commit
+			commit.Stats = detail.Stats
+			commit.Files = detail.Files
+		}(&commits[i])
+	}
+	wg.Wait()
+
+	return firstErr
+}
+
+func (c *Client) get(accessToken, url string, v interface{}) (http.Header, error) {
+	for attempt := 0; ; attempt++ {
+		if err := c.waitForRateLimit(accessToken, url); err != nil {
+			return nil, err
+		}
+
+		header, err := c.doGet(accessToken, url, v)
+		if err == nil {
+			return header, nil
+		}
+
+		delay, retry := retryDelay(err, attempt)
+		if !retry || attempt >= maxRetries {
+			return nil, err
+		}
+		time.Sleep(delay)
+	}
+}
+
+// 用于httpapi.GetAll逐页请求
+func (c *Client) getter(accessToken string) func(endpoint string, v interface{}) (http.Header, error) {
+	return func(endpoint string, v interface{}) (http.Header, error) {
+		return c.get(accessToken, endpoint, v)
+	}
+}
+
+func (c *Client) doGet(accessToken, url string, v interface{}) (http.Header, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, err
@@ -190,21 +319,60 @@ func (c *Client) GetRepositoryCommits(accessToken, repoFullName, author string,
 	req.Header.Set("Authorization", "token "+accessToken)
 	req.Header.Set("Accept", "application/vnd.github.v3+json")
 
+	var key string
+	var cached *CachedResponse
+	if c.cache != nil {
+		key = cacheKey(accessToken, url)
+		if entry, ok := c.cache.Get(key); ok {
+			cached = entry
+			if cached.ETag != "" {
+				req.Header.Set("If-None-Match", cached.ETag)
+			}
+			if cached.LastModified != "" {
+				req.Header.Set("If-Modified-Since", cached.LastModified)
+			}
+		}
+	}
+
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	c.updateRateLimit(accessToken, resp.Header)
+
+	// 内容未变化，使用缓存的响应
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		header := resp.Header.Clone()
+		header.Set("Link", cached.Link)
+		if err := json.Unmarshal(cached.Body, v); err != nil {
+			return nil, err
+		}
+		return header, nil
+	}
+
+	body, err := httpapi.ReadBody(resp.Body)
 	if err != nil {
 		return nil, err
 	}
 
-	var commits []Commit
-	if err := json.Unmarshal(body, &commits); err != nil {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, newAPIError(resp, url, body)
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
 		return nil, err
 	}
 
-	return commits, nil
-}
\ No newline at end of file
+	if c.cache != nil && (resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != "") {
+		c.cache.Set(key, &CachedResponse{
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			Link:         resp.Header.Get("Link"),
+			Body:         body,
+		})
+	}
+
+	return resp.Header, nil
+}
@@ -0,0 +1,17 @@
+package github
+
+import (
+	"errors"
+	"myvault-backend/pkg/httpapi"
+)
+
+var (
+	ErrRateLimited  = errors.New("github: rate limit exceeded")
+	ErrNotFound     = errors.New("github: not found")
+	ErrUnauthorized = errors.New("github: unauthorized")
+	// 令牌有效但无权访问该资源，例如组织限制了OAuth应用，只影响单个仓库
+	ErrForbidden = errors.New("github: forbidden")
+)
+
+// APIError 表示GitHub返回的非2xx响应，可用errors.Is匹配上面的哨兵错误，限流时ResetAt为可以重试的时间
+type APIError = httpapi.Error
@@ -0,0 +1,150 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"myvault-backend/pkg/httpapi"
+	"time"
+)
+
+// GitHub事件流最多返回300条事件
+const maxFeedEvents = 300
+
+const (
+	PushEventType              = "PushEvent"
+	PullRequestEventType       = "PullRequestEvent"
+	PullRequestReviewEventType = "PullRequestReviewEvent"
+	IssuesEventType            = "IssuesEvent"
+)
+
+type Event struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Repo struct {
+		Name string `json:"name"`
+	} `json:"repo"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type PushEventPayload struct {
+	Ref     string `json:"ref"`
+	Size    int    `json:"size"`
+	Commits []struct {
+		SHA     string `json:"sha"`
+		Message string `json:"message"`
+		Author  struct {
+			Name  string `json:"name"`
+			Email string `json:"email"`
+		} `json:"author"`
+		Distinct bool `json:"distinct"`
+	} `json:"commits"`
+}
+
+type PullRequestEventPayload struct {
+	Action      string      `json:"action"`
+	Number      int         `json:"number"`
+	PullRequest PullRequest `json:"pull_request"`
+}
+
+type PullRequestReviewEventPayload struct {
+	Action      string      `json:"action"`
+	Review      Review      `json:"review"`
+	PullRequest PullRequest `json:"pull_request"`
+}
+
+type IssuesEventPayload struct {
+	Action string `json:"action"`
+	Issue  Issue  `json:"issue"`
+}
+
+type PullRequest struct {
+	Number    int        `json:"number"`
+	Title     string     `json:"title"`
+	State     string     `json:"state"`
+	HTMLURL   string     `json:"html_url"`
+	Merged    bool       `json:"merged"`
+	Additions int        `json:"additions"`
+	Deletions int        `json:"deletions"`
+	CreatedAt time.Time  `json:"created_at"`
+	MergedAt  *time.Time `json:"merged_at"`
+}
+
+type Review struct {
+	ID          int       `json:"id"`
+	State       string    `json:"state"`
+	HTMLURL     string    `json:"html_url"`
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+type Issue struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	State   string `json:"state"`
+	HTMLURL string `json:"html_url"`
+}
+
+func (e *Event) PushPayload() (*PushEventPayload, error) {
+	var payload PushEventPayload
+	if err := e.decodePayload(PushEventType, &payload); err != nil {
+		return nil, err
+	}
+	return &payload, nil
+}
+
+func (e *Event) PullRequestPayload() (*PullRequestEventPayload, error) {
+	var payload PullRequestEventPayload
+	if err := e.decodePayload(PullRequestEventType, &payload); err != nil {
+		return nil, err
+	}
+	return &payload, nil
+}
+
+func (e *Event) ReviewPayload() (*PullRequestReviewEventPayload, error) {
+	var payload PullRequestReviewEventPayload
+	if err := e.decodePayload(PullRequestReviewEventType, &payload); err != nil {
+		return nil, err
+	}
+	return &payload, nil
+}
+
+func (e *Event) IssuesPayload() (*IssuesEventPayload, error) {
+	var payload IssuesEventPayload
+	if err := e.decodePayload(IssuesEventType, &payload); err != nil {
+		return nil, err
+	}
+	return &payload, nil
+}
+
+func (e *Event) decodePayload(eventType string, v interface{}) error {
+	if e.Type != eventType {
+		return fmt.Errorf("github: event %s is %s, not %s", e.ID, e.Type, eventType)
+	}
+	return json.Unmarshal(e.Payload, v)
+}
+
+// GetUserEvents 获取用户在since之后的事件，按时间倒序返回。
+// complete为false表示事件流已达上限而未覆盖到since，调用方需要用其他方式补全
+func (c *Client) GetUserEvents(accessToken, username string, since time.Time) (events []Event, complete bool, err error) {
+	url := fmt.Sprintf("https://api.github.com/users/%s/events?per_page=100", username)
+
+	total := 0
+	for url != "" {
+		var page []Event
+		header, err := c.get(accessToken, url, &page)
+		if err != nil {
+			return nil, false, err
+		}
+		total += len(page)
+
+		for _, event := range page {
+			if event.CreatedAt.Before(since) {
+				return events, true, nil
+			}
+			events = append(events, event)
+		}
+		url = httpapi.NextLink(url, header)
+	}
+
+	return events, total < maxFeedEvents, nil
+}
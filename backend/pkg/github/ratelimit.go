@@ -0,0 +1,142 @@
+package github
+
+import (
+	"errors"
+	"myvault-backend/pkg/httpapi"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxRetries     = 3
+	retryBaseDelay = time.Second
+	// 限流等待超过该时长时不再阻塞，直接返回ErrRateLimited让调用方推迟
+	maxRateLimitWait = time.Minute
+)
+
+type rateLimit struct {
+	remaining int
+	resetAt   time.Time
+}
+
+// 根据响应头记录该令牌的剩余配额
+func (c *Client) updateRateLimit(accessToken string, header http.Header) {
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.rateLimits[accessToken] = rateLimit{remaining: remaining, resetAt: time.Unix(reset, 0)}
+	c.mu.Unlock()
+}
+
+// 配额已耗尽时在请求前等待，等待时间过长则返回限流错误
+func (c *Client) waitForRateLimit(accessToken, url string) error {
+	c.mu.Lock()
+	limit, ok := c.rateLimits[accessToken]
+	c.mu.Unlock()
+
+	if !ok || limit.remaining > 0 {
+		return nil
+	}
+
+	wait := time.Until(limit.resetAt)
+	if wait <= 0 {
+		return nil
+	}
+	if wait > maxRateLimitWait {
+		return &APIError{
+			Service:    "github",
+			StatusCode: http.StatusForbidden,
+			Message:    "rate limit exhausted, request deferred",
+			URL:        url,
+			ResetAt:    limit.resetAt,
+			Kind:       ErrRateLimited,
+		}
+	}
+
+	time.Sleep(wait)
+	return nil
+}
+
+// 判断请求是否值得重试，并给出重试前的等待时间
+func retryDelay(err error, attempt int) (time.Duration, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case errors.Is(err, ErrRateLimited):
+			wait := time.Until(apiErr.ResetAt)
+			if wait > maxRateLimitWait {
+				return 0, false
+			}
+			if wait < 0 {
+				wait = 0
+			}
+			return wait + time.Second, true
+		case apiErr.StatusCode >= 500:
+			return retryBaseDelay << attempt, true
+		}
+		return 0, false
+	}
+
+	// 网络错误同样按指数退避重试
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return retryBaseDelay << attempt, true
+	}
+
+	return 0, false
+}
+
+func newAPIError(resp *http.Response, url string, body []byte) *APIError {
+	apiErr := &APIError{
+		Service:    "github",
+		StatusCode: resp.StatusCode,
+		Message:    httpapi.ErrorMessage(body, "message"),
+		URL:        url,
+	}
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		apiErr.Kind = ErrUnauthorized
+	case http.StatusNotFound:
+		apiErr.Kind = ErrNotFound
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		resetAt, ok := rateLimitResetAt(resp.Header)
+		switch {
+		case ok || resp.StatusCode == http.StatusTooManyRequests:
+			apiErr.Kind = ErrRateLimited
+			apiErr.ResetAt = resetAt
+		case strings.Contains(strings.ToLower(apiErr.Message), "rate limit"):
+			// 二级限流不一定带有Retry-After
+			apiErr.Kind = ErrRateLimited
+		default:
+			apiErr.Kind = ErrForbidden
+		}
+	}
+
+	return apiErr
+}
+
+// 优先使用Retry-After（二级限流），其次在剩余配额为0时使用X-RateLimit-Reset
+func rateLimitResetAt(header http.Header) (time.Time, bool) {
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil {
+		return time.Now().Add(time.Duration(seconds) * time.Second), true
+	}
+
+	if header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return time.Unix(reset, 0), true
+		}
+	}
+
+	return time.Time{}, false
+}
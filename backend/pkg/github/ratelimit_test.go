@@ -0,0 +1,50 @@
+package github
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestNewAPIError(t *testing.T) {
+	reset := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name    string
+		status  int
+		header  map[string]string
+		body    string
+		want    error
+		resetAt bool
+	}{
+		{"unauthorized", http.StatusUnauthorized, nil, `{"message": "Bad credentials"}`, ErrUnauthorized, false},
+		{"not found", http.StatusNotFound, nil, `{"message": "Not Found"}`, ErrNotFound, false},
+		{"primary rate limit", http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset}, `{"message": "API rate limit exceeded"}`, ErrRateLimited, true},
+		{"retry after", http.StatusForbidden, map[string]string{"Retry-After": "30"}, `{"message": "slow down"}`, ErrRateLimited, true},
+		{"secondary rate limit without headers", http.StatusForbidden, nil, `{"message": "You have exceeded a secondary rate limit"}`, ErrRateLimited, false},
+		{"too many requests", http.StatusTooManyRequests, nil, ``, ErrRateLimited, false},
+		{"organization restriction", http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "4999"}, `{"message": "Although you appear to have the correct authorization credentials, the organization has enabled OAuth App access restrictions"}`, ErrForbidden, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			for key, value := range tt.header {
+				resp.Header.Set(key, value)
+			}
+
+			err := newAPIError(resp, "https://api.github.com/repos/o/r/commits", []byte(tt.body))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("error = %v, want %v", err, tt.want)
+			}
+			if errors.Is(err, ErrUnauthorized) && tt.want != ErrUnauthorized {
+				t.Errorf("%v should not be treated as unauthorized", err)
+			}
+			if got := !err.ResetAt.IsZero(); got != tt.resetAt {
+				t.Errorf("ResetAt = %v, want set %v", err.ResetAt, tt.resetAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package github
+
+import (
+	"fmt"
+	"myvault-backend/pkg/httpapi"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type SearchIssue struct {
+	ID            int    `json:"id"`
+	Number        int    `json:"number"`
+	Title         string `json:"title"`
+	State         string `json:"state"`
+	HTMLURL       string `json:"html_url"`
+	RepositoryURL string `json:"repository_url"`
+	// 仅当结果是Pull Request时存在
+	PullRequest *struct {
+		MergedAt *time.Time `json:"merged_at"`
+	} `json:"pull_request"`
+	CreatedAt time.Time  `json:"created_at"`
+	ClosedAt  *time.Time `json:"closed_at"`
+}
+
+func (i SearchIssue) IsPullRequest() bool {
+	return i.PullRequest != nil
+}
+
+// Repository 从repository_url中解析出owner/name
+func (i SearchIssue) Repository() string {
+	return strings.TrimPrefix(i.RepositoryURL, "https://api.github.com/repos/")
+}
+
+type searchIssuesResponse struct {
+	TotalCount int           `json:"total_count"`
+	Items      []SearchIssue `json:"items"`
+}
+
+// SearchUserIssues 搜索用户自since起创建的issue和Pull Request。搜索接口最多返回1000条结果
+func (c *Client) SearchUserIssues(accessToken, username string, since time.Time) ([]SearchIssue, error) {
+	query := fmt.Sprintf("author:%s created:>=%s", username, since.Format("2006-01-02"))
+	endpoint := "https://api.github.com/search/issues?per_page=100&sort=created&order=desc&q=" + url.QueryEscape(query)
+
+	var all []SearchIssue
+	for endpoint != "" {
+		var page searchIssuesResponse
+		header, err := c.get(accessToken, endpoint, &page)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page.Items...)
+		endpoint = httpapi.NextLink(endpoint, header)
+	}
+
+	return all, nil
+}
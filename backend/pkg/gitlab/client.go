@@ -0,0 +1,162 @@
+package gitlab
+
+import (
+	"errors"
+	"fmt"
+	"myvault-backend/pkg/httpapi"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound     = errors.New("gitlab: not found")
+	ErrUnauthorized = errors.New("gitlab: unauthorized")
+	// 令牌有效但权限不足，如令牌缺少read_repository或被移出了项目所在的组
+	ErrForbidden   = errors.New("gitlab: forbidden")
+	ErrRateLimited = errors.New("gitlab: rate limit exceeded")
+)
+
+// APIError 表示GitLab返回的非2xx响应，可用errors.Is匹配上面的哨兵错误
+type APIError = httpapi.Error
+
+type Client struct {
+	baseURL string
+	api     *httpapi.Client
+}
+
+type User struct {
+	ID          int    `json:"id"`
+	Username    string `json:"username"`
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	PublicEmail string `json:"public_email"`
+	AvatarURL   string `json:"avatar_url"`
+}
+
+type Project struct {
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	Description       string `json:"description"`
+	Visibility        string `json:"visibility"`
+	Archived          bool   `json:"archived"`
+	ForkedFromProject *struct {
+		ID int `json:"id"`
+	} `json:"forked_from_project"`
+}
+
+func (p Project) Fork() bool {
+	return p.ForkedFromProject != nil
+}
+
+type Commit struct {
+	ID           string    `json:"id"`
+	Title        string    `json:"title"`
+	Message      string    `json:"message"`
+	AuthorName   string    `json:"author_name"`
+	AuthorEmail  string    `json:"author_email"`
+	AuthoredDate time.Time `json:"authored_date"`
+	Stats        struct {
+		Additions int `json:"additions"`
+		Deletions int `json:"deletions"`
+		Total     int `json:"total"`
+	} `json:"stats"`
+	Project string `json:"-"` // 由GetProjectCommits填充
+}
+
+// baseURL为GitLab实例地址，如 https://gitlab.com 或自建实例地址
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		api: &httpapi.Client{
+			Service:    "gitlab",
+			HTTPClient: &http.Client{Timeout: 30 * time.Second},
+			Classify: httpapi.Kinds{
+				Unauthorized: ErrUnauthorized,
+				Forbidden:    ErrForbidden,
+				NotFound:     ErrNotFound,
+				RateLimited:  ErrRateLimited,
+			}.Classify,
+			// message可能是字符串或按字段分组的对象，OAuth相关的错误放在error中
+			MessageFields: []string{"message", "error"},
+		},
+	}
+}
+
+// SetHTTPClient 替换发送请求的客户端，访问用户填写的实例地址时使用safehttp
+func (c *Client) SetHTTPClient(httpClient *http.Client) {
+	c.api.HTTPClient = httpClient
+}
+
+// 个人访问令牌和OAuth令牌都通过Bearer方式认证
+func (c *Client) GetUser(accessToken string) (*User, error) {
+	var user User
+	if _, err := c.get(accessToken, c.baseURL+"/api/v4/user", &user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// 获取用户作为成员的所有项目
+func (c *Client) GetProjects(accessToken string) ([]Project, error) {
+	endpoint := c.baseURL + "/api/v4/projects?membership=true&simple=false&order_by=last_activity_at&per_page=100"
+	return httpapi.GetAll[Project](endpoint, c.getter(accessToken), nextPageURL)
+}
+
+// 获取项目默认分支上since之后的提交，author匹配提交者的名字或邮箱，为空时不过滤
+func (c *Client) GetProjectCommits(accessToken string, project Project, author string, since time.Time) ([]Commit, error) {
+	params := url.Values{}
+	params.Set("since", since.Format(time.RFC3339))
+	params.Set("with_stats", "true")
+	params.Set("per_page", "100")
+	if author != "" {
+		params.Set("author", author)
+	}
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/repository/commits?%s", c.baseURL, project.ID, params.Encode())
+
+	all, err := httpapi.GetAll[Commit](endpoint, c.getter(accessToken), nextPageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range all {
+		all[i].Project = project.PathWithNamespace
+	}
+
+	return all, nil
+}
+
+func (c *Client) get(accessToken, endpoint string, v interface{}) (http.Header, error) {
+	return c.api.Get(endpoint, "Bearer "+accessToken, v)
+}
+
+// 用于httpapi.GetAll逐页请求
+func (c *Client) getter(accessToken string) func(endpoint string, v interface{}) (http.Header, error) {
+	return func(endpoint string, v interface{}) (http.Header, error) {
+		return c.get(accessToken, endpoint, v)
+	}
+}
+
+// GitLab同时返回Link和X-Next-Page头，优先使用Link
+func nextPageURL(endpoint string, header http.Header) string {
+	if next := httpapi.NextLink(endpoint, header); next != "" {
+		return next
+	}
+
+	nextPage := header.Get("X-Next-Page")
+	if nextPage == "" {
+		return ""
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return ""
+	}
+	query := u.Query()
+	query.Set("page", nextPage)
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
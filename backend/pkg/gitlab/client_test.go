@@ -0,0 +1,155 @@
+package gitlab
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewClient(server.URL + "/")
+}
+
+func TestGetUser(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/user" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q", got)
+		}
+		fmt.Fprint(w, `{"id": 7, "username": "alice", "name": "Alice", "email": "alice@example.com"}`)
+	})
+
+	user, err := client.GetUser("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != 7 || user.Username != "alice" || user.Email != "alice@example.com" {
+		t.Errorf("unexpected user %+v", user)
+	}
+}
+
+func TestGetProjectsPagination(t *testing.T) {
+	var serverURL string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "":
+			// 第一页使用Link头
+			w.Header().Set("Link", fmt.Sprintf(`<%s/api/v4/projects?page=2>; rel="next", <%s/api/v4/projects?page=3>; rel="last"`, serverURL, serverURL))
+			fmt.Fprint(w, `[{"id": 1, "path_with_namespace": "team/one"}]`)
+		case "2":
+			// 第二页只有X-Next-Page
+			w.Header().Set("X-Next-Page", "3")
+			fmt.Fprint(w, `[{"id": 2, "path_with_namespace": "team/two", "forked_from_project": {"id": 9}}]`)
+		case "3":
+			fmt.Fprint(w, `[{"id": 3, "path_with_namespace": "team/three", "archived": true}]`)
+		default:
+			t.Errorf("unexpected page %q", r.URL.Query().Get("page"))
+		}
+	})
+	serverURL = client.baseURL
+
+	projects, err := client.GetProjects("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != 3 {
+		t.Fatalf("got %d projects, want 3", len(projects))
+	}
+	if projects[0].Fork() || !projects[1].Fork() || !projects[2].Archived {
+		t.Errorf("unexpected projects %+v", projects)
+	}
+}
+
+func TestGetProjectCommits(t *testing.T) {
+	since := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/projects/42/repository/commits" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		query := r.URL.Query()
+		if query.Get("since") != "2024-05-01T00:00:00Z" || query.Get("with_stats") != "true" || query.Get("author") != "alice@example.com" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		fmt.Fprint(w, `[{
+			"id": "abc123",
+			"title": "Fix login",
+			"message": "Fix login\n\nDetails",
+			"author_name": "Alice",
+			"author_email": "alice@example.com",
+			"authored_date": "2024-05-02T10:30:00+08:00",
+			"stats": {"additions": 12, "deletions": 3, "total": 15}
+		}]`)
+	})
+
+	commits, err := client.GetProjectCommits("secret", Project{ID: 42, PathWithNamespace: "team/app"}, "alice@example.com", since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 1 {
+		t.Fatalf("got %d commits, want 1", len(commits))
+	}
+	commit := commits[0]
+	if commit.ID != "abc123" || commit.Project != "team/app" || commit.Stats.Additions != 12 || commit.Stats.Deletions != 3 {
+		t.Errorf("unexpected commit %+v", commit)
+	}
+	if want := time.Date(2024, 5, 2, 2, 30, 0, 0, time.UTC); !commit.AuthoredDate.Equal(want) {
+		t.Errorf("AuthoredDate = %v, want %v", commit.AuthoredDate, want)
+	}
+}
+
+// 状态码分类和错误信息截断由httpapi测试，这里只检查GitLab的映射和错误字段
+func TestAPIErrors(t *testing.T) {
+	tests := []struct {
+		status  int
+		body    string
+		want    error
+		message string
+	}{
+		{http.StatusUnauthorized, `{"message": "401 Unauthorized"}`, ErrUnauthorized, "401 Unauthorized"},
+		{http.StatusForbidden, `{"error": "insufficient_scope"}`, ErrForbidden, "insufficient_scope"},
+		{http.StatusNotFound, `{"message": "404 Project Not Found"}`, ErrNotFound, "404 Project Not Found"},
+		{http.StatusTooManyRequests, `Retry later`, ErrRateLimited, "Retry later"},
+		{http.StatusInternalServerError, `{"message": {"base": ["broken"]}}`, nil, "map[base:[broken]]"},
+	}
+
+	for _, tt := range tests {
+		client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			fmt.Fprint(w, tt.body)
+		})
+
+		_, err := client.GetUser("secret")
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("status %d: expected APIError, got %v", tt.status, err)
+		}
+		if apiErr.StatusCode != tt.status || apiErr.Message != tt.message {
+			t.Errorf("status %d: unexpected error %+v", tt.status, apiErr)
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("status %d: expected %v, got %v", tt.status, tt.want, err)
+		}
+		// 403只影响单个项目，不能被当作令牌失效而中止同步
+		if errors.Is(err, ErrUnauthorized) && tt.want != ErrUnauthorized {
+			t.Errorf("status %d: should not be treated as unauthorized", tt.status)
+		}
+	}
+}
+
+func TestInvalidJSON(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `not json`)
+	})
+
+	if _, err := client.GetUser("secret"); err == nil {
+		t.Fatal("expected error for invalid JSON")
+	}
+}
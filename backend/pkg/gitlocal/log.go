@@ -0,0 +1,134 @@
+package gitlocal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 单个仓库执行git log的最长时间
+const logTimeout = time.Minute
+
+// 记录和字段分隔符，提交说明可能包含换行，用\x1d标记说明结束，其后是numstat输出
+const (
+	recordSeparator  = "\x1e"
+	fieldSeparator   = "\x1f"
+	messageSeparator = "\x1d"
+	logFormat        = "%x1e%H%x1f%an%x1f%ae%x1f%aI%x1f%B%x1d"
+)
+
+var ErrNotRepository = errors.New("gitlocal: not a git repository")
+
+type Commit struct {
+	SHA         string
+	AuthorName  string
+	AuthorEmail string
+	Date        time.Time
+	Message     string
+	Files       int
+	Additions   int
+	Deletions   int
+}
+
+type LogOptions struct {
+	// 按作者邮箱精确匹配，为空时不过滤
+	AuthorEmail string
+	Since       time.Time
+	Until       time.Time
+	// 为true时扫描所有分支，否则只扫描HEAD
+	AllBranches bool
+}
+
+// Log 读取仓库在时间范围内的提交，按时间倒序返回
+func Log(repoPath string, opts LogOptions) ([]Commit, error) {
+	args := []string{"-C", repoPath, "log", "--no-color", "--numstat", "--date-order", "--format=" + logFormat}
+	if opts.AllBranches {
+		args = append(args, "--all")
+	}
+	if opts.AuthorEmail != "" {
+		// --author匹配"名字 <邮箱>"，用尖括号限定为邮箱的精确匹配
+		args = append(args, "--fixed-strings", "--regexp-ignore-case", "--author=<"+opts.AuthorEmail+">")
+	}
+	if !opts.Since.IsZero() {
+		args = append(args, "--since="+opts.Since.Format(time.RFC3339))
+	}
+	if !opts.Until.IsZero() {
+		args = append(args, "--until="+opts.Until.Format(time.RFC3339))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), logTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if strings.Contains(message, "not a git repository") {
+			return nil, fmt.Errorf("%w: %s", ErrNotRepository, repoPath)
+		}
+		// 空仓库没有HEAD，视为没有提交
+		if strings.Contains(message, "does not have any commits yet") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("gitlocal: git log %s: %v: %s", repoPath, err, message)
+	}
+
+	return parseLog(stdout.String())
+}
+
+func parseLog(output string) ([]Commit, error) {
+	var commits []Commit
+	for _, record := range strings.Split(output, recordSeparator) {
+		if strings.TrimSpace(record) == "" {
+			continue
+		}
+
+		header, stats, ok := strings.Cut(record, messageSeparator)
+		if !ok {
+			return nil, fmt.Errorf("gitlocal: malformed log record %q", record)
+		}
+		fields := strings.SplitN(header, fieldSeparator, 5)
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("gitlocal: malformed log header %q", header)
+		}
+
+		date, err := time.Parse(time.RFC3339, fields[3])
+		if err != nil {
+			return nil, err
+		}
+
+		commit := Commit{
+			SHA:         fields[0],
+			AuthorName:  fields[1],
+			AuthorEmail: fields[2],
+			Date:        date,
+			Message:     strings.TrimSpace(fields[4]),
+		}
+
+		// numstat每行为"新增\t删除\t路径"，二进制文件的行数为"-"
+		for _, line := range strings.Split(stats, "\n") {
+			parts := strings.SplitN(line, "\t", 3)
+			if len(parts) != 3 {
+				continue
+			}
+			commit.Files++
+			if additions, err := strconv.Atoi(parts[0]); err == nil {
+				commit.Additions += additions
+			}
+			if deletions, err := strconv.Atoi(parts[1]); err == nil {
+				commit.Deletions += deletions
+			}
+		}
+
+		commits = append(commits, commit)
+	}
+
+	return commits, nil
+}
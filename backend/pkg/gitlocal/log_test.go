@@ -0,0 +1,167 @@
+package gitlocal
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 按logFormat拼出git log的输出
+func record(sha, name, email, date, message, numstat string) string {
+	return recordSeparator + strings.Join([]string{sha, name, email, date, message}, fieldSeparator) + messageSeparator + numstat
+}
+
+func TestParseLog(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    []Commit
+		wantErr bool
+	}{
+		{
+			name:   "empty output",
+			output: "",
+		},
+		{
+			name:   "commit with numstat",
+			output: record("a1", "Alice", "alice@example.com", "2024-05-02T10:30:00+08:00", "Fix login\n", "\n12\t3\tauth/login.go\n4\t0\tauth/login_test.go\n"),
+			want: []Commit{{
+				SHA: "a1", AuthorName: "Alice", AuthorEmail: "alice@example.com",
+				Date:    time.Date(2024, 5, 2, 2, 30, 0, 0, time.UTC),
+				Message: "Fix login", Files: 2, Additions: 16, Deletions: 3,
+			}},
+		},
+		{
+			name: "multi-line message and binary file",
+			output: record("b2", "Bob", "bob@example.com", "2024-05-03T09:00:00Z", "Add logo\n\nUsed on the login page.\n", "\n-\t-\tassets/logo.png\n1\t1\tREADME.md\n") +
+				record("c3", "Bob", "bob@example.com", "2024-05-02T09:00:00Z", "Merge branch 'main'\n", "\n"),
+			want: []Commit{
+				{
+					SHA: "b2", AuthorName: "Bob", AuthorEmail: "bob@example.com",
+					Date:    time.Date(2024, 5, 3, 9, 0, 0, 0, time.UTC),
+					Message: "Add logo\n\nUsed on the login page.", Files: 2, Additions: 1, Deletions: 1,
+				},
+				{
+					SHA: "c3", AuthorName: "Bob", AuthorEmail: "bob@example.com",
+					Date:    time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC),
+					Message: "Merge branch 'main'",
+				},
+			},
+		},
+		{
+			// 提交说明中的制表符不能被当作numstat
+			name:   "tabs in message",
+			output: record("d4", "Carol", "carol@example.com", "2024-05-04T12:00:00Z", "Align\ttable\tcolumns\n", "\n2\t2\ttable.go\n"),
+			want: []Commit{{
+				SHA: "d4", AuthorName: "Carol", AuthorEmail: "carol@example.com",
+				Date:    time.Date(2024, 5, 4, 12, 0, 0, 0, time.UTC),
+				Message: "Align\ttable\tcolumns", Files: 1, Additions: 2, Deletions: 2,
+			}},
+		},
+		{
+			name:    "missing message separator",
+			output:  recordSeparator + "e5" + fieldSeparator + "Dave",
+			wantErr: true,
+		},
+		{
+			name:    "missing fields",
+			output:  recordSeparator + "e5" + fieldSeparator + "Dave" + messageSeparator,
+			wantErr: true,
+		},
+		{
+			name:    "invalid date",
+			output:  record("f6", "Eve", "eve@example.com", "yesterday", "Oops", ""),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLog(tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLog error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d commits, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				if !got[i].Date.Equal(tt.want[i].Date) {
+					t.Errorf("commit %d: Date = %v, want %v", i, got[i].Date, tt.want[i].Date)
+				}
+				got[i].Date = tt.want[i].Date
+				if got[i] != tt.want[i] {
+					t.Errorf("commit %d: got %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// 用真实的git仓库检查参数和输出格式
+func TestLog(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	git := func(env []string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(), "GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1")
+		cmd.Env = append(cmd.Env, env...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	commit := func(name, email, date, file, content, message string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		git(nil, "add", file)
+		git([]string{
+			"GIT_AUTHOR_NAME=" + name, "GIT_AUTHOR_EMAIL=" + email, "GIT_AUTHOR_DATE=" + date,
+			"GIT_COMMITTER_NAME=" + name, "GIT_COMMITTER_EMAIL=" + email, "GIT_COMMITTER_DATE=" + date,
+		}, "commit", "-q", "-m", message)
+	}
+
+	git(nil, "init", "-q")
+	if commits, err := Log(dir, LogOptions{}); err != nil || commits != nil {
+		t.Fatalf("empty repository: got %v, %v", commits, err)
+	}
+
+	commit("Alice", "alice@example.com", "2024-05-01T10:00:00Z", "a.txt", "one\ntwo\n", "First\n\nBody line")
+	commit("Bob", "bob@example.com", "2024-05-02T10:00:00Z", "b.txt", "three\n", "Second")
+	commit("Alice", "Alice@Example.com", "2024-05-03T10:00:00Z", "a.txt", "one\n", "Third")
+
+	all, err := Log(dir, LogOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 || all[0].Message != "Third" || all[2].Message != "First\n\nBody line" {
+		t.Fatalf("unexpected commits %+v", all)
+	}
+	if all[0].Files != 1 || all[0].Additions != 0 || all[0].Deletions != 1 {
+		t.Errorf("unexpected stats %+v", all[0])
+	}
+
+	// 邮箱不区分大小写，且不会匹配到名字
+	mine, err := Log(dir, LogOptions{
+		AuthorEmail: "alice@example.com",
+		Since:       time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mine) != 1 || mine[0].Message != "Third" {
+		t.Errorf("unexpected filtered commits %+v", mine)
+	}
+
+	if _, err := Log(t.TempDir(), LogOptions{}); !errors.Is(err, ErrNotRepository) {
+		t.Errorf("expected ErrNotRepository, got %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+// Package httpapi 提供GitHub、GitLab、Gitea和WakaTime客户端共用的请求、错误和分页处理，
+// 响应体大小限制和错误信息截断也用于模型服务的客户端
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// 错误信息中保留的最大长度，避免把整页HTML写入日志和同步结果
+	maxMessageLength = 200
+	// 响应体的大小上限
+	maxBodySize = 32 << 20
+)
+
+var ErrBodyTooLarge = errors.New("httpapi: response body too large")
+
+// Error 表示非2xx响应，Kind为各客户端定义的哨兵错误，可用errors.Is匹配
+type Error struct {
+	Service    string
+	StatusCode int
+	Message    string
+	URL        string
+	Kind       error
+	// 限流时可以重试的时间，服务没有提供时为零值
+	ResetAt time.Time
+}
+
+func (e *Error) Error() string {
+	if !e.ResetAt.IsZero() {
+		return fmt.Sprintf("%s: %s returned %d (rate limited until %s): %s",
+			e.Service, e.URL, e.StatusCode, e.ResetAt.Format(time.RFC3339), e.Message)
+	}
+	return fmt.Sprintf("%s: %s returned %d: %s", e.Service, e.URL, e.StatusCode, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// Kinds 各类错误状态码对应的哨兵错误，为nil的类别不分类
+type Kinds struct {
+	Unauthorized error // 401
+	Forbidden    error // 403
+	NotFound     error // 404
+	RateLimited  error // 429
+}
+
+// Classify 可直接作为Client.Classify使用
+func (k Kinds) Classify(statusCode int) error {
+	switch statusCode {
+	case http.StatusUnauthorized:
+		return k.Unauthorized
+	case http.StatusForbidden:
+		return k.Forbidden
+	case http.StatusNotFound:
+		return k.NotFound
+	case http.StatusTooManyRequests:
+		return k.RateLimited
+	}
+	return nil
+}
+
+// Client 发送带认证的GET请求并解析JSON响应
+type Client struct {
+	// 错误信息的前缀，如gitea
+	Service    string
+	HTTPClient *http.Client
+	// 根据状态码返回哨兵错误，没有对应的错误时返回nil
+	Classify func(statusCode int) error
+	// 错误响应中存放错误信息的JSON字段，按顺序取第一个非空值
+	MessageFields []string
+}
+
+// Get 请求endpoint并将JSON响应解析到v，返回响应头用于分页
+func (c *Client) Get(endpoint, authorization string, v interface{}) (http.Header, error) {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ReadBody(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &Error{
+			Service:    c.Service,
+			StatusCode: resp.StatusCode,
+			Message:    ErrorMessage(body, c.MessageFields...),
+			URL:        endpoint,
+		}
+		if c.Classify != nil {
+			apiErr.Kind = c.Classify(resp.StatusCode)
+		}
+		return nil, apiErr
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return nil, err
+	}
+
+	return resp.Header, nil
+}
+
+// ReadBody 读取响应体，超过大小上限时返回ErrBodyTooLarge
+func ReadBody(r io.Reader) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r, maxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxBodySize {
+		return nil, ErrBodyTooLarge
+	}
+	return body, nil
+}
+
+// ErrorMessage 从JSON响应中取第一个非空的字段作为错误信息，都没有时使用响应体，超长的部分截断
+func ErrorMessage(body []byte, fields ...string) string {
+	var result map[string]interface{}
+	if err := json.Unmarshal(body, &result); err == nil {
+		for _, field := range fields {
+			if value, ok := result[field]; ok && value != nil && value != "" {
+				return Truncate(fmt.Sprint(value))
+			}
+		}
+	}
+	return Truncate(strings.TrimSpace(string(body)))
+}
+
+// Truncate 截断过长的错误信息
+func Truncate(s string) string {
+	if len(s) <= maxMessageLength {
+		return s
+	}
+	// 不在多字节字符中间截断
+	cut := maxMessageLength
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "...(truncated)"
+}
+
+// GetAll 从endpoint开始依次请求每一页，next根据当前地址和响应头返回下一页地址，为空时结束
+func GetAll[T any](endpoint string, get func(endpoint string, v interface{}) (http.Header, error), next func(endpoint string, header http.Header) string) ([]T, error) {
+	var all []T
+	for endpoint != "" {
+		var page []T
+		header, err := get(endpoint, &page)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		endpoint = next(endpoint, header)
+	}
+
+	return all, nil
+}
+
+// NextLink 解析形如 <https://...&page=2>; rel="next", <...>; rel="last" 的Link头，
+// 没有下一页时返回空字符串。endpoint未使用，签名与GetAll的next参数一致
+func NextLink(endpoint string, header http.Header) string {
+	for _, part := range strings.Split(header.Get("Link"), ",") {
+		segments := strings.Split(strings.TrimSpace(part), ";")
+		if len(segments) < 2 {
+			continue
+		}
+		for _, param := range segments[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(segments[0]), "<>")
+			}
+		}
+	}
+	return ""
+}
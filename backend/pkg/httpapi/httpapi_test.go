@@ -0,0 +1,150 @@
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+var errNotFound = errors.New("test: not found")
+
+func TestGetAll(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "token secret" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/items?page=2>; rel="next"`, server.URL))
+			fmt.Fprint(w, `[1, 2]`)
+			return
+		}
+		fmt.Fprint(w, `[3]`)
+	}))
+	defer server.Close()
+
+	client := &Client{Service: "test", HTTPClient: server.Client()}
+	items, err := GetAll[int](server.URL+"/items", func(endpoint string, v interface{}) (http.Header, error) {
+		return client.Get(endpoint, "token secret", v)
+	}, NextLink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(items) != "[1 2 3]" {
+		t.Errorf("items = %v", items)
+	}
+}
+
+func TestGetError(t *testing.T) {
+	page := strings.Repeat("<p>服务暂时不可用</p>", 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error": "", "message": "Not Found"}`)
+			return
+		}
+		w.WriteHeader(http.StatusBadGateway)
+		fmt.Fprint(w, page)
+	}))
+	defer server.Close()
+
+	client := &Client{
+		Service:    "test",
+		HTTPClient: server.Client(),
+		Classify: func(statusCode int) error {
+			if statusCode == http.StatusNotFound {
+				return errNotFound
+			}
+			return nil
+		},
+		MessageFields: []string{"error", "message"},
+	}
+
+	var v interface{}
+	_, err := client.Get(server.URL+"/missing", "", &v)
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || !errors.Is(err, errNotFound) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+	if apiErr.Message != "Not Found" {
+		t.Errorf("Message = %q", apiErr.Message)
+	}
+	if !strings.HasPrefix(err.Error(), "test: ") || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Error() = %q", err.Error())
+	}
+
+	_, err = client.Get(server.URL+"/html", "", &v)
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway || apiErr.Kind != nil {
+		t.Fatalf("expected bad gateway error, got %v", err)
+	}
+	if len(apiErr.Message) > maxMessageLength+len("...(truncated)") || !strings.HasSuffix(apiErr.Message, "...(truncated)") {
+		t.Errorf("message not truncated: %d bytes", len(apiErr.Message))
+	}
+	if !utf8.ValidString(apiErr.Message) {
+		t.Errorf("truncated message is not valid UTF-8: %q", apiErr.Message)
+	}
+}
+
+func TestKindsClassify(t *testing.T) {
+	errUnauthorized := errors.New("test: unauthorized")
+	errForbidden := errors.New("test: forbidden")
+	errRateLimited := errors.New("test: rate limited")
+	kinds := Kinds{Unauthorized: errUnauthorized, Forbidden: errForbidden, NotFound: errNotFound, RateLimited: errRateLimited}
+
+	tests := []struct {
+		kinds  Kinds
+		status int
+		want   error
+	}{
+		{kinds, http.StatusUnauthorized, errUnauthorized},
+		{kinds, http.StatusForbidden, errForbidden},
+		{kinds, http.StatusNotFound, errNotFound},
+		{kinds, http.StatusTooManyRequests, errRateLimited},
+		{kinds, http.StatusBadRequest, nil},
+		{kinds, http.StatusBadGateway, nil},
+		// 没有设置的类别不分类
+		{Kinds{Unauthorized: errUnauthorized}, http.StatusNotFound, nil},
+		// 403可以与401使用相同的错误
+		{Kinds{Unauthorized: errUnauthorized, Forbidden: errUnauthorized}, http.StatusForbidden, errUnauthorized},
+	}
+
+	for _, tt := range tests {
+		if got := tt.kinds.Classify(tt.status); got != tt.want {
+			t.Errorf("Classify(%d) = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestReadBodyLimit(t *testing.T) {
+	if _, err := ReadBody(strings.NewReader(strings.Repeat("a", maxBodySize+1))); !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("expected ErrBodyTooLarge, got %v", err)
+	}
+	body, err := ReadBody(strings.NewReader("ok"))
+	if err != nil || string(body) != "ok" {
+		t.Errorf("ReadBody = %q, %v", body, err)
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	tests := []struct {
+		link string
+		want string
+	}{
+		{"", ""},
+		{`<https://git.example.com/api/v1/user/repos?page=2>; rel="next"`, "https://git.example.com/api/v1/user/repos?page=2"},
+		{`<https://git.example.com/a?page=1>; rel="prev", <https://git.example.com/a?page=3>; rel="next"`, "https://git.example.com/a?page=3"},
+		{`<https://git.example.com/a?page=5>; rel="last"`, ""},
+	}
+
+	for _, tt := range tests {
+		header := http.Header{}
+		header.Set("Link", tt.link)
+		if got := NextLink("", header); got != tt.want {
+			t.Errorf("NextLink(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Event 是日历中的一个VEVENT。重复事件的RRULE、EXDATE保存在事件上，由Expand展开
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Status      string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+	RRule       *RRule
+	ExDates     []time.Time
+	// 非零时表示这是重复事件中某一次的修改，值为被替换的那次的原始开始时间
+	RecurrenceID time.Time
+
+	// DURATION和RRULE可能出现在DTSTART之前，在END:VEVENT时再处理
+	duration time.Duration
+	rrule    string
+	// 时区无法识别，时间不可靠
+	skip bool
+}
+
+func (e *Event) Duration() time.Duration {
+	return e.End.Sub(e.Start)
+}
+
+type Calendar struct {
+	Name   string
+	Events []Event
+	// 无法识别的TZID，使用这些时区的事件已跳过
+	UnknownTimezones []string
+}
+
+// property 是一行内容，如 DTSTART;TZID=Asia/Shanghai:20240101T090000
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse 解析iCalendar内容。TZID可以是IANA时区名、Windows时区名或日历中VTIMEZONE定义的时区，
+// 都无法识别时跳过使用该时区的事件并记录在UnknownTimezones中；不带时区的浮动时间按defaultLoc解释
+func Parse(r io.Reader, defaultLoc *time.Location) (*Calendar, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar := &Calendar{}
+	zones := &zoneResolver{timezones: parseTimezones(lines), cache: make(map[string]*time.Location)}
+	var current *Event
+	var depth []string
+	for i, line := range lines {
+		prop, ok := parseProperty(line)
+		if !ok {
+			// 不包含行内容，内容可能来自任意地址
+			return nil, fmt.Errorf("ical: malformed line %d", i+1)
+		}
+
+		switch prop.name {
+		case "BEGIN":
+			depth = append(depth, strings.ToUpper(prop.value))
+			if strings.EqualFold(prop.value, "VEVENT") {
+				current = &Event{}
+			}
+			continue
+		case "END":
+			if len(depth) > 0 {
+				depth = depth[:len(depth)-1]
+			}
+			if strings.EqualFold(prop.value, "VEVENT") && current != nil {
+				if !current.Start.IsZero() && !current.skip {
+					if err := current.finish(); err != nil {
+						return nil, err
+					}
+					calendar.Events = append(calendar.Events, *current)
+				}
+				current = nil
+			}
+			continue
+		}
+
+		// 只处理VEVENT本身的属性，忽略VALARM等子组件和VTIMEZONE
+		if current == nil || len(depth) == 0 || depth[len(depth)-1] != "VEVENT" {
+			if prop.name == "X-WR-CALNAME" && len(depth) == 1 {
+				calendar.Name = unescapeText(prop.value)
+			}
+			continue
+		}
+
+		if err := current.set(prop, defaultLoc, zones); err != nil {
+			if errors.Is(err, ErrUnknownTimezone) {
+				current.skip = true
+				calendar.addUnknownTimezone(prop.params["TZID"])
+				continue
+			}
+			return nil, fmt.Errorf("ical: %s: %w", prop.name, err)
+		}
+	}
+
+	return calendar, nil
+}
+
+func (c *Calendar) addUnknownTimezone(tzid string) {
+	for _, existing := range c.UnknownTimezones {
+		if existing == tzid {
+			return
+		}
+	}
+	c.UnknownTimezones = append(c.UnknownTimezones, tzid)
+}
+
+func (e *Event) set(prop property, defaultLoc *time.Location, zones *zoneResolver) error {
+	switch prop.name {
+	case "UID":
+		e.UID = prop.value
+	case "SUMMARY":
+		e.Summary = unescapeText(prop.value)
+	case "DESCRIPTION":
+		e.Description = unescapeText(prop.value)
+	case "LOCATION":
+		e.Location = unescapeText(prop.value)
+	case "STATUS":
+		e.Status = strings.ToUpper(prop.value)
+	case "DTSTART":
+		t, allDay, err := parseDateTime(prop, defaultLoc, zones)
+		if err != nil {
+			return err
+		}
+		e.Start, e.AllDay = t, allDay
+	case "DTEND":
+		t, _, err := parseDateTime(prop, defaultLoc, zones)
+		if err != nil {
+			return err
+		}
+		e.End = t
+	case "DURATION":
+		d, err := parseDuration(prop.value)
+		if err != nil {
+			return err
+		}
+		e.duration = d
+	case "RRULE":
+		e.rrule = prop.value
+	case "EXDATE":
+		for _, value := range strings.Split(prop.value, ",") {
+			t, _, err := parseDateTime(property{name: prop.name, params: prop.params, value: value}, defaultLoc, zones)
+			if err != nil {
+				return err
+			}
+			e.ExDates = append(e.ExDates, t)
+		}
+	case "RECURRENCE-ID":
+		t, _, err := parseDateTime(prop, defaultLoc, zones)
+		if err != nil {
+			return err
+		}
+		e.RecurrenceID = t
+	}
+	return nil
+}
+
+// 补全结束时间并解析重复规则
+func (e *Event) finish() error {
+	if e.End.IsZero() {
+		switch {
+		case e.duration != 0:
+			e.End = e.Start.Add(e.duration)
+		case e.AllDay:
+			e.End = e.Start.AddDate(0, 0, 1)
+		default:
+			e.End = e.Start
+		}
+	}
+
+	if e.rrule != "" {
+		rule, err := ParseRRule(e.rrule, e.Start.Location())
+		if err != nil {
+			return fmt.Errorf("ical: RRULE: %w", err)
+		}
+		e.RRule = rule
+	}
+
+	return nil
+}
+
+// 合并折行：以空格或制表符开头的行是上一行的延续
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, scanner.Err()
+}
+
+func parseProperty(line string) (property, bool) {
+	// 参数值可能用双引号包含冒号，找到引号外的第一个冒号
+	inQuote := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			inQuote = !inQuote
+		} else if r == ':' && !inQuote {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return property{}, false
+	}
+
+	prop := property{value: line[colon+1:], params: make(map[string]string)}
+	parts := strings.Split(line[:colon], ";")
+	prop.name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		key, value, ok := strings.Cut(param, "=")
+		if ok {
+			prop.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+
+	return prop, true
+}
+
+// 解析DATE或DATE-TIME，返回值是否为全天日期
+func parseDateTime(prop property, defaultLoc *time.Location, zones *zoneResolver) (time.Time, bool, error) {
+	value := strings.TrimSpace(prop.value)
+
+	// UTC时间忽略TZID
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+
+	loc := defaultLoc
+	if tzid := prop.params["TZID"]; tzid != "" {
+		var err error
+		if loc, err = zones.load(tzid); err != nil {
+			return time.Time{}, false, err
+		}
+	}
+
+	if prop.params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t, true, err
+	}
+
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+// 解析如 PT1H30M、P1D 的时长
+func parseDuration(value string) (time.Duration, error) {
+	sign := time.Duration(1)
+	if strings.HasPrefix(value, "-") {
+		sign = -1
+	}
+	value = strings.TrimLeft(value, "+-")
+	if !strings.HasPrefix(value, "P") {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	var total time.Duration
+	var number int
+	inTime := false
+	for _, r := range value[1:] {
+		switch {
+		case r >= '0' && r <= '9':
+			number = number*10 + int(r-'0')
+			continue
+		case r == 'T':
+			inTime = true
+		case r == 'W':
+			total += time.Duration(number) * 7 * 24 * time.Hour
+		case r == 'D':
+			total += time.Duration(number) * 24 * time.Hour
+		case r == 'H' && inTime:
+			total += time.Duration(number) * time.Hour
+		case r == 'M' && inTime:
+			total += time.Duration(number) * time.Minute
+		case r == 'S' && inTime:
+			total += time.Duration(number) * time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		number = 0
+	}
+
+	return sign * total, nil
+}
+
+func unescapeText(value string) string {
+	replacer := strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+	return replacer.Replace(value)
+}
@@ -0,0 +1,378 @@
+package ical
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// 展开重复规则时最多遍历的周期数，防止没有COUNT和UNTIL的规则无限循环
+const maxPeriods = 50000
+
+// WeekdayNum 对应BYDAY中的一项，如 2MO 表示第二个周一，-1FR 表示最后一个周五，N为0表示每个
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+// RRule 支持RFC 5545中常用的子集：FREQ、INTERVAL、COUNT、UNTIL、BYDAY、BYMONTHDAY、BYMONTH、WKST。
+// 不支持按小时及更细的频率和BYSETPOS等其余规则
+type RRule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+	WeekStart  time.Weekday
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// ParseRRule 解析RRULE的值，loc为DTSTART的时区，用于解释不带Z的UNTIL
+func ParseRRule(value string, loc *time.Location) (*RRule, error) {
+	rule := &RRule{Interval: 1, WeekStart: time.Monday}
+
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		val = strings.ToUpper(strings.TrimSpace(val))
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq = Frequency(val)
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %q", val)
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid COUNT %q", val)
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, err := parseUntil(val, loc)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = until
+		case "BYDAY":
+			for _, item := range strings.Split(val, ",") {
+				if len(item) < 2 {
+					return nil, fmt.Errorf("invalid BYDAY %q", item)
+				}
+				day, ok := weekdays[item[len(item)-2:]]
+				if !ok {
+					return nil, fmt.Errorf("invalid BYDAY %q", item)
+				}
+				n := 0
+				if prefix := item[:len(item)-2]; prefix != "" {
+					var err error
+					if n, err = strconv.Atoi(prefix); err != nil {
+						return nil, fmt.Errorf("invalid BYDAY %q", item)
+					}
+				}
+				rule.ByDay = append(rule.ByDay, WeekdayNum{N: n, Day: day})
+			}
+		case "BYMONTHDAY":
+			for _, item := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(item)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("invalid BYMONTHDAY %q", item)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		case "BYMONTH":
+			for _, item := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(item)
+				if err != nil || n < 1 || n > 12 {
+					return nil, fmt.Errorf("invalid BYMONTH %q", item)
+				}
+				rule.ByMonth = append(rule.ByMonth, time.Month(n))
+			}
+		case "WKST":
+			day, ok := weekdays[val]
+			if !ok {
+				return nil, fmt.Errorf("invalid WKST %q", val)
+			}
+			rule.WeekStart = day
+		}
+	}
+
+	switch rule.Freq {
+	case Daily, Weekly, Monthly, Yearly:
+	default:
+		return nil, fmt.Errorf("unsupported FREQ %q", rule.Freq)
+	}
+
+	return rule, nil
+}
+
+// UNTIL为日期时包含当天
+func parseUntil(value string, loc *time.Location) (time.Time, error) {
+	switch {
+	case len(value) == 8:
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t.AddDate(0, 0, 1).Add(-time.Nanosecond), err
+	case strings.HasSuffix(value, "Z"):
+		return time.Parse("20060102T150405Z", value)
+	default:
+		return time.ParseInLocation("20060102T150405", value, loc)
+	}
+}
+
+// Expand 返回[from, to)内发生的所有事件，重复事件按RRULE展开为单次事件（RRule为nil）。
+// EXDATE排除的和已取消的不返回，RECURRENCE-ID修改过的用修改后的版本代替，结果按开始时间排序
+func Expand(events []Event, from, to time.Time) []Event {
+	// 被单独修改过的重复事件实例：UID -> 原始开始时间
+	overridden := make(map[string]map[int64]bool)
+	for _, e := range events {
+		if e.RecurrenceID.IsZero() {
+			continue
+		}
+		if overridden[e.UID] == nil {
+			overridden[e.UID] = make(map[int64]bool)
+		}
+		overridden[e.UID][e.RecurrenceID.Unix()] = true
+	}
+
+	var result []Event
+	for _, e := range events {
+		if e.Status == "CANCELLED" {
+			continue
+		}
+
+		if e.RRule == nil || !e.RecurrenceID.IsZero() {
+			if overlaps(e.Start, e.End, from, to) {
+				e.RRule, e.ExDates = nil, nil
+				result = append(result, e)
+			}
+			continue
+		}
+
+		excluded := make(map[int64]bool, len(e.ExDates))
+		for _, t := range e.ExDates {
+			excluded[t.Unix()] = true
+		}
+
+		duration := e.Duration()
+		for _, start := range e.occurrences(to) {
+			if excluded[start.Unix()] || overridden[e.UID][start.Unix()] {
+				continue
+			}
+
+			end := start.Add(duration)
+			if e.AllDay {
+				// 全天事件按日历天数计算，避免夏令时切换导致的偏差
+				days := int(duration.Hours()+12) / 24
+				end = start.AddDate(0, 0, days)
+			}
+			if !overlaps(start, end, from, to) {
+				continue
+			}
+
+			occurrence := e
+			occurrence.Start, occurrence.End = start, end
+			occurrence.RRule, occurrence.ExDates = nil, nil
+			result = append(result, occurrence)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Start.Before(result[j].Start)
+	})
+
+	return result
+}
+
+func overlaps(start, end, from, to time.Time) bool {
+	if !start.Before(to) {
+		return false
+	}
+	return end.After(from) || !start.Before(from)
+}
+
+// 返回所有早于to的实例开始时间。时间按DTSTART所在时区的本地时间生成，跨夏令时保持钟点不变
+func (e *Event) occurrences(to time.Time) []time.Time {
+	rule := e.RRule
+	start := e.Start
+	loc := start.Location()
+
+	var result []time.Time
+	count := 0
+	for period := 0; period < maxPeriods; period++ {
+		for _, day := range rule.candidates(start, period) {
+			t := time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), start.Second(), 0, loc)
+			if t.Before(start) {
+				continue
+			}
+			if !rule.Until.IsZero() && t.After(rule.Until) {
+				return result
+			}
+			if !t.Before(to) {
+				return result
+			}
+
+			result = append(result, t)
+			count++
+			if rule.Count > 0 && count >= rule.Count {
+				return result
+			}
+		}
+	}
+
+	return result
+}
+
+// 第period个周期内满足规则的日期，按时间顺序返回
+func (r *RRule) candidates(start time.Time, period int) []time.Time {
+	loc := start.Location()
+	step := period * r.Interval
+	var days []time.Time
+
+	switch r.Freq {
+	case Daily:
+		day := time.Date(start.Year(), start.Month(), start.Day()+step, 0, 0, 0, 0, loc)
+		if r.matchMonth(day) && r.matchMonthDay(day) && r.matchWeekday(day) {
+			days = append(days, day)
+		}
+	case Weekly:
+		offset := (int(start.Weekday()) - int(r.WeekStart) + 7) % 7
+		weekStart := time.Date(start.Year(), start.Month(), start.Day()-offset+7*step, 0, 0, 0, 0, loc)
+		for i := 0; i < 7; i++ {
+			day := weekStart.AddDate(0, 0, i)
+			if len(r.ByDay) == 0 && day.Weekday() != start.Weekday() {
+				continue
+			}
+			if r.matchMonth(day) && r.matchWeekday(day) {
+				days = append(days, day)
+			}
+		}
+	case Monthly:
+		month := time.Date(start.Year(), start.Month()+time.Month(step), 1, 0, 0, 0, 0, loc)
+		if r.matchMonth(month) {
+			days = r.daysInRange(start, month, month.AddDate(0, 1, 0))
+		}
+	case Yearly:
+		year := start.Year() + step
+		if len(r.ByMonth) == 0 && len(r.ByMonthDay) == 0 && len(r.ByDay) > 0 {
+			// 没有BYMONTH时BYDAY的序号按全年计算，如 20MO 表示一年中第20个周一
+			first := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+			return r.daysInRange(start, first, first.AddDate(1, 0, 0))
+		}
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{start.Month()}
+		}
+		sorted := append([]time.Month(nil), months...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		for _, m := range sorted {
+			month := time.Date(year, m, 1, 0, 0, 0, 0, loc)
+			days = append(days, r.daysInRange(start, month, month.AddDate(0, 1, 0))...)
+		}
+	}
+
+	return days
+}
+
+// [first, end)内满足BYMONTHDAY和BYDAY的日期，两者都没有时取DTSTART的日
+func (r *RRule) daysInRange(start, first, end time.Time) []time.Time {
+	var days []time.Time
+	for day := first; day.Before(end); day = day.AddDate(0, 0, 1) {
+		switch {
+		case len(r.ByMonthDay) == 0 && len(r.ByDay) == 0:
+			if day.Day() != start.Day() {
+				continue
+			}
+		case len(r.ByMonthDay) > 0 && !r.matchMonthDay(day):
+			continue
+		}
+		if len(r.ByDay) > 0 && !r.matchWeekdayNum(day, first, end) {
+			continue
+		}
+		days = append(days, day)
+	}
+	return days
+}
+
+func (r *RRule) matchMonth(day time.Time) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, m := range r.ByMonth {
+		if day.Month() == m {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RRule) matchMonthDay(day time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	daysInMonth := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, day.Location()).Day()
+	for _, n := range r.ByMonthDay {
+		if n == day.Day() || (n < 0 && daysInMonth+n+1 == day.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+// 只比较星期，忽略序号，用于DAILY和WEEKLY
+func (r *RRule) matchWeekday(day time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Day == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// 带序号的星期匹配，序号在[first, end)范围内计算
+func (r *RRule) matchWeekdayNum(day, first, end time.Time) bool {
+	for _, wd := range r.ByDay {
+		if wd.Day != day.Weekday() {
+			continue
+		}
+		if wd.N == 0 {
+			return true
+		}
+		if wd.N > 0 && int(day.Sub(first).Hours()+12)/24/7+1 == wd.N {
+			return true
+		}
+		if wd.N < 0 && int(end.Sub(day).Hours()-12)/24/7+1 == -wd.N {
+			return true
+		}
+	}
+	return false
+}
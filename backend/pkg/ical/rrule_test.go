@@ -0,0 +1,219 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestExpandRRule(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+	at := func(loc *time.Location, year int, month time.Month, day, hour int) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, loc)
+	}
+	utc := func(year int, month time.Month, day int) time.Time {
+		return at(time.UTC, year, month, day, 9)
+	}
+
+	tests := []struct {
+		name  string
+		rrule string
+		start time.Time
+		to    time.Time
+		want  []time.Time
+	}{
+		{
+			name:  "daily count",
+			rrule: "FREQ=DAILY;COUNT=3",
+			start: utc(2024, 1, 1),
+			want:  []time.Time{utc(2024, 1, 1), utc(2024, 1, 2), utc(2024, 1, 3)},
+		},
+		{
+			name:  "daily until date-time is inclusive",
+			rrule: "FREQ=DAILY;UNTIL=20240103T090000Z",
+			start: utc(2024, 1, 1),
+			want:  []time.Time{utc(2024, 1, 1), utc(2024, 1, 2), utc(2024, 1, 3)},
+		},
+		{
+			name:  "open-ended rule stops at the range end",
+			rrule: "FREQ=DAILY",
+			start: utc(2024, 1, 1),
+			to:    time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC),
+			want:  []time.Time{utc(2024, 1, 1), utc(2024, 1, 2), utc(2024, 1, 3)},
+		},
+		{
+			name:  "weekly byday",
+			rrule: "FREQ=WEEKLY;BYDAY=MO,WE,FR;COUNT=5",
+			start: utc(2024, 1, 1),
+			want:  []time.Time{utc(2024, 1, 1), utc(2024, 1, 3), utc(2024, 1, 5), utc(2024, 1, 8), utc(2024, 1, 10)},
+		},
+		{
+			name:  "biweekly until date includes the last day",
+			rrule: "FREQ=WEEKLY;INTERVAL=2;UNTIL=20240129",
+			start: utc(2024, 1, 1),
+			want:  []time.Time{utc(2024, 1, 1), utc(2024, 1, 15), utc(2024, 1, 29)},
+		},
+		{
+			// 开始日期不满足BYDAY时不算作一次
+			name:  "weekly byday skips dtstart",
+			rrule: "FREQ=WEEKLY;BYDAY=TU;COUNT=2",
+			start: utc(2024, 1, 1),
+			want:  []time.Time{utc(2024, 1, 2), utc(2024, 1, 9)},
+		},
+		{
+			name:  "monthly last friday",
+			rrule: "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3",
+			start: utc(2024, 1, 26),
+			want:  []time.Time{utc(2024, 1, 26), utc(2024, 2, 23), utc(2024, 3, 29)},
+		},
+		{
+			name:  "monthly second monday",
+			rrule: "FREQ=MONTHLY;BYDAY=2MO;COUNT=3",
+			start: utc(2024, 1, 8),
+			want:  []time.Time{utc(2024, 1, 8), utc(2024, 2, 12), utc(2024, 3, 11)},
+		},
+		{
+			name:  "monthly last day",
+			rrule: "FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=3",
+			start: utc(2024, 1, 31),
+			want:  []time.Time{utc(2024, 1, 31), utc(2024, 2, 29), utc(2024, 3, 31)},
+		},
+		{
+			// 没有31日的月份跳过
+			name:  "monthly on the 31st",
+			rrule: "FREQ=MONTHLY;COUNT=3",
+			start: utc(2024, 1, 31),
+			want:  []time.Time{utc(2024, 1, 31), utc(2024, 3, 31), utc(2024, 5, 31)},
+		},
+		{
+			name:  "yearly leap day",
+			rrule: "FREQ=YEARLY;COUNT=2",
+			start: utc(2024, 2, 29),
+			to:    time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+			want:  []time.Time{utc(2024, 2, 29), utc(2028, 2, 29)},
+		},
+		{
+			// 夏令时开始后仍是当地时间9点
+			name:  "weekly across daylight saving time",
+			rrule: "FREQ=WEEKLY;COUNT=3",
+			start: at(newYork, 2024, 3, 3, 9),
+			want:  []time.Time{at(newYork, 2024, 3, 3, 9), at(newYork, 2024, 3, 10, 9), at(newYork, 2024, 3, 17, 9)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRRule(tt.rrule, tt.start.Location())
+			if err != nil {
+				t.Fatal(err)
+			}
+			to := tt.to
+			if to.IsZero() {
+				to = tt.start.AddDate(1, 0, 0)
+			}
+
+			event := Event{UID: "e", Start: tt.start, End: tt.start.Add(time.Hour), RRule: rule}
+			got := Expand([]Event{event}, tt.start, to)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d occurrences, want %d: %v", len(got), len(tt.want), starts(got))
+			}
+			for i, e := range got {
+				if !e.Start.Equal(tt.want[i]) || e.Start.Hour() != tt.want[i].Hour() {
+					t.Errorf("occurrence %d = %v, want %v", i, e.Start, tt.want[i])
+				}
+				if e.Duration() != time.Hour || e.RRule != nil {
+					t.Errorf("occurrence %d not expanded: %+v", i, e)
+				}
+			}
+		})
+	}
+}
+
+func TestExpandExDatesAndOverrides(t *testing.T) {
+	const ics = `BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:standup
+SUMMARY:Standup
+DTSTART;TZID=Asia/Shanghai:20240101T093000
+DTEND;TZID=Asia/Shanghai:20240101T094500
+RRULE:FREQ=DAILY;COUNT=5
+EXDATE;TZID=Asia/Shanghai:20240102T093000,20240104T093000
+END:VEVENT
+BEGIN:VEVENT
+UID:standup
+SUMMARY:Standup (moved)
+RECURRENCE-ID;TZID=Asia/Shanghai:20240103T093000
+DTSTART;TZID=Asia/Shanghai:20240103T140000
+DTEND;TZID=Asia/Shanghai:20240103T141500
+END:VEVENT
+BEGIN:VEVENT
+UID:cancelled
+STATUS:CANCELLED
+DTSTART:20240101T100000Z
+DTEND:20240101T110000Z
+END:VEVENT
+END:VCALENDAR
+`
+	shanghai := mustLoad(t, "Asia/Shanghai")
+	calendar, err := Parse(strings.NewReader(ics), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, shanghai)
+	got := Expand(calendar.Events, from, from.AddDate(0, 0, 10))
+
+	want := []struct {
+		start   time.Time
+		summary string
+	}{
+		{time.Date(2024, 1, 1, 9, 30, 0, 0, shanghai), "Standup"},
+		{time.Date(2024, 1, 3, 14, 0, 0, 0, shanghai), "Standup (moved)"},
+		{time.Date(2024, 1, 5, 9, 30, 0, 0, shanghai), "Standup"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d: %v", len(got), len(want), starts(got))
+	}
+	for i, e := range got {
+		if !e.Start.Equal(want[i].start) || e.Summary != want[i].summary || e.Duration() != 15*time.Minute {
+			t.Errorf("event %d = %s at %v for %v", i, e.Summary, e.Start, e.Duration())
+		}
+	}
+}
+
+func TestParseRRuleErrors(t *testing.T) {
+	for _, value := range []string{
+		"FREQ=HOURLY",
+		"COUNT=3",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=x",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=MONTHLY;BYDAY=aMO",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=YEARLY;BYMONTH=13",
+		"FREQ=WEEKLY;WKST=ZZ",
+		"FREQ=DAILY;UNTIL=tomorrow",
+	} {
+		if _, err := ParseRRule(value, time.UTC); err == nil {
+			t.Errorf("ParseRRule(%q) expected error", value)
+		}
+	}
+}
+
+func starts(events []Event) []time.Time {
+	var result []time.Time
+	for _, e := range events {
+		result = append(result, e.Start)
+	}
+	return result
+}
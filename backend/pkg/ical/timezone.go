@@ -0,0 +1,353 @@
+package ical
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrUnknownTimezone = errors.New("ical: unknown time zone")
+
+// Outlook和Exchange导出的日历使用Windows时区名，取CLDR windowsZones中各时区的默认城市
+var windowsZones = map[string]string{
+	"Dateline Standard Time":          "Etc/GMT+12",
+	"UTC-11":                          "Etc/GMT+11",
+	"Hawaiian Standard Time":          "Pacific/Honolulu",
+	"Alaskan Standard Time":           "America/Anchorage",
+	"Pacific Standard Time (Mexico)":  "America/Tijuana",
+	"Pacific Standard Time":           "America/Los_Angeles",
+	"US Mountain Standard Time":       "America/Phoenix",
+	"Mountain Standard Time (Mexico)": "America/Mazatlan",
+	"Mountain Standard Time":          "America/Denver",
+	"Central America Standard Time":   "America/Guatemala",
+	"Central Standard Time":           "America/Chicago",
+	"Central Standard Time (Mexico)":  "America/Mexico_City",
+	"Canada Central Standard Time":    "America/Regina",
+	"SA Pacific Standard Time":        "America/Bogota",
+	"Eastern Standard Time":           "America/New_York",
+	"Eastern Standard Time (Mexico)":  "America/Cancun",
+	"US Eastern Standard Time":        "America/Indianapolis",
+	"Venezuela Standard Time":         "America/Caracas",
+	"Atlantic Standard Time":          "America/Halifax",
+	"SA Western Standard Time":        "America/La_Paz",
+	"Pacific SA Standard Time":        "America/Santiago",
+	"Newfoundland Standard Time":      "America/St_Johns",
+	"E. South America Standard Time":  "America/Sao_Paulo",
+	"Argentina Standard Time":         "America/Buenos_Aires",
+	"SA Eastern Standard Time":        "America/Cayenne",
+	"Greenland Standard Time":         "America/Godthab",
+	"UTC-02":                          "Etc/GMT+2",
+	"Azores Standard Time":            "Atlantic/Azores",
+	"Cape Verde Standard Time":        "Atlantic/Cape_Verde",
+	"UTC":                             "Etc/UTC",
+	"GMT Standard Time":               "Europe/London",
+	"Greenwich Standard Time":         "Atlantic/Reykjavik",
+	"Morocco Standard Time":           "Africa/Casablanca",
+	"W. Europe Standard Time":         "Europe/Berlin",
+	"Central Europe Standard Time":    "Europe/Budapest",
+	"Romance Standard Time":           "Europe/Paris",
+	"Central European Standard Time":  "Europe/Warsaw",
+	"W. Central Africa Standard Time": "Africa/Lagos",
+	"GTB Standard Time":               "Europe/Bucharest",
+	"FLE Standard Time":               "Europe/Kiev",
+	"E. Europe Standard Time":         "Europe/Chisinau",
+	"Egypt Standard Time":             "Africa/Cairo",
+	"South Africa Standard Time":      "Africa/Johannesburg",
+	"Israel Standard Time":            "Asia/Jerusalem",
+	"Turkey Standard Time":            "Europe/Istanbul",
+	"Arab Standard Time":              "Asia/Riyadh",
+	"Arabic Standard Time":            "Asia/Baghdad",
+	"Russian Standard Time":           "Europe/Moscow",
+	"E. Africa Standard Time":         "Africa/Nairobi",
+	"Iran Standard Time":              "Asia/Tehran",
+	"Arabian Standard Time":           "Asia/Dubai",
+	"Afghanistan Standard Time":       "Asia/Kabul",
+	"Pakistan Standard Time":          "Asia/Karachi",
+	"West Asia Standard Time":         "Asia/Tashkent",
+	"India Standard Time":             "Asia/Calcutta",
+	"Sri Lanka Standard Time":         "Asia/Colombo",
+	"Nepal Standard Time":             "Asia/Katmandu",
+	"Central Asia Standard Time":      "Asia/Almaty",
+	"Bangladesh Standard Time":        "Asia/Dhaka",
+	"Myanmar Standard Time":           "Asia/Rangoon",
+	"SE Asia Standard Time":           "Asia/Bangkok",
+	"China Standard Time":             "Asia/Shanghai",
+	"Singapore Standard Time":         "Asia/Singapore",
+	"Taipei Standard Time":            "Asia/Taipei",
+	"W. Australia Standard Time":      "Australia/Perth",
+	"Tokyo Standard Time":             "Asia/Tokyo",
+	"Korea Standard Time":             "Asia/Seoul",
+	"Cen. Australia Standard Time":    "Australia/Adelaide",
+	"AUS Central Standard Time":       "Australia/Darwin",
+	"E. Australia Standard Time":      "Australia/Brisbane",
+	"AUS Eastern Standard Time":       "Australia/Sydney",
+	"West Pacific Standard Time":      "Pacific/Port_Moresby",
+	"Tasmania Standard Time":          "Australia/Hobart",
+	"Vladivostok Standard Time":       "Asia/Vladivostok",
+	"Central Pacific Standard Time":   "Pacific/Guadalcanal",
+	"New Zealand Standard Time":       "Pacific/Auckland",
+	"UTC+12":                          "Etc/GMT-12",
+	"Fiji Standard Time":              "Pacific/Fiji",
+	"Tonga Standard Time":             "Pacific/Tongatapu",
+}
+
+// VTIMEZONE组件，STANDARD和DAYLIGHT各为一个observance
+type vtimezone struct {
+	id          string
+	location    string // X-LIC-LOCATION，通常为IANA时区名
+	observances []observance
+	invalid     bool
+}
+
+type observance struct {
+	daylight   bool
+	name       string
+	start      time.Time // 本地时间，按UTC保存
+	offsetFrom int       // 秒
+	offsetTo   int
+	rrule      string
+	rdates     []time.Time
+}
+
+// 生成时区规则的截止时间，TZif v1使用32位时间
+var tzifEnd = time.Date(2038, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// 日历中用到的时区，按TZID缓存
+type zoneResolver struct {
+	timezones map[string]*vtimezone
+	cache     map[string]*time.Location
+}
+
+// TZID依次按IANA时区名、VTIMEZONE的X-LIC-LOCATION、Windows时区名和VTIMEZONE中的规则解析
+func (r *zoneResolver) load(tzid string) (*time.Location, error) {
+	if loc, ok := r.cache[tzid]; ok {
+		return loc, nil
+	}
+
+	loc, err := r.resolve(tzid)
+	if err != nil {
+		return nil, err
+	}
+	r.cache[tzid] = loc
+	return loc, nil
+}
+
+func (r *zoneResolver) resolve(tzid string) (*time.Location, error) {
+	// 部分客户端使用 /mozilla.org/20050126_1/Europe/Berlin 这样的前缀
+	names := []string{tzid}
+	if parts := strings.Split(strings.Trim(tzid, "/"), "/"); len(parts) > 2 {
+		names = append(names, strings.Join(parts[len(parts)-2:], "/"))
+	}
+	tz := r.timezones[tzid]
+	if tz != nil && tz.location != "" {
+		names = append(names, tz.location)
+	}
+	if name, ok := windowsZones[tzid]; ok {
+		names = append(names, name)
+	}
+
+	for _, name := range names {
+		// 空字符串和Local会得到UTC和服务器时区，不是日历指定的时区
+		if name == "" || name == "Local" {
+			continue
+		}
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc, nil
+		}
+	}
+
+	if tz != nil && !tz.invalid {
+		if loc, err := tz.load(); err == nil {
+			return loc, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w %q", ErrUnknownTimezone, tzid)
+}
+
+// 收集日历中的VTIMEZONE，VTIMEZONE可以出现在使用它的VEVENT之后
+func parseTimezones(lines []string) map[string]*vtimezone {
+	timezones := make(map[string]*vtimezone)
+	var current *vtimezone
+	var obs *observance
+	for _, line := range lines {
+		prop, ok := parseProperty(line)
+		if !ok {
+			continue
+		}
+		value := strings.TrimSpace(prop.value)
+
+		switch {
+		case prop.name == "BEGIN" && strings.EqualFold(value, "VTIMEZONE"):
+			current = &vtimezone{}
+		case current == nil:
+			// VTIMEZONE之外的内容由Parse处理
+		case prop.name == "END" && strings.EqualFold(value, "VTIMEZONE"):
+			if current.id != "" {
+				timezones[current.id] = current
+			}
+			current = nil
+		case prop.name == "BEGIN" && (strings.EqualFold(value, "STANDARD") || strings.EqualFold(value, "DAYLIGHT")):
+			obs = &observance{daylight: strings.EqualFold(value, "DAYLIGHT")}
+		case obs == nil:
+			switch prop.name {
+			case "TZID":
+				current.id = value
+			case "X-LIC-LOCATION":
+				current.location = value
+			}
+		case prop.name == "END":
+			current.observances = append(current.observances, *obs)
+			obs = nil
+		default:
+			if err := obs.set(prop.name, value); err != nil {
+				current.invalid = true
+			}
+		}
+	}
+	return timezones
+}
+
+func (o *observance) set(name, value string) error {
+	var err error
+	switch name {
+	case "TZNAME":
+		o.name = value
+	case "DTSTART":
+		o.start, err = time.Parse("20060102T150405", value)
+	case "TZOFFSETFROM":
+		o.offsetFrom, err = parseOffset(value)
+	case "TZOFFSETTO":
+		o.offsetTo, err = parseOffset(value)
+	case "RRULE":
+		o.rrule = value
+	case "RDATE":
+		for _, item := range strings.Split(value, ",") {
+			t, err := time.Parse("20060102T150405", item)
+			if err != nil {
+				return err
+			}
+			o.rdates = append(o.rdates, t)
+		}
+	}
+	return err
+}
+
+// 如 +0800、-0430、+053000
+func parseOffset(value string) (int, error) {
+	if (len(value) != 5 && len(value) != 7) || (value[0] != '+' && value[0] != '-') {
+		return 0, fmt.Errorf("invalid offset %q", value)
+	}
+	digits := value[1:] + "00"
+	hours, err1 := strconv.Atoi(digits[0:2])
+	minutes, err2 := strconv.Atoi(digits[2:4])
+	seconds, err3 := strconv.Atoi(digits[4:6])
+	if err1 != nil || err2 != nil || err3 != nil || minutes > 59 || seconds > 59 {
+		return 0, fmt.Errorf("invalid offset %q", value)
+	}
+	offset := hours*3600 + minutes*60 + seconds
+	if value[0] == '-' {
+		offset = -offset
+	}
+	return offset, nil
+}
+
+// 按VTIMEZONE的规则生成各次切换的时间，构造成TZif数据后由time包加载
+func (tz *vtimezone) load() (*time.Location, error) {
+	if len(tz.observances) == 0 {
+		return nil, fmt.Errorf("%w %q", ErrUnknownTimezone, tz.id)
+	}
+
+	type transition struct {
+		at  int64
+		typ int
+	}
+	var transitions []transition
+	for i, obs := range tz.observances {
+		if obs.start.IsZero() {
+			return nil, fmt.Errorf("%w %q", ErrUnknownTimezone, tz.id)
+		}
+
+		starts := append([]time.Time{obs.start}, obs.rdates...)
+		if obs.rrule != "" {
+			rule, err := ParseRRule(obs.rrule, time.UTC)
+			if err != nil {
+				return nil, err
+			}
+			// UNTIL为UTC时间，换算为与DTSTART相同的本地时间
+			if until := rruleValue(obs.rrule, "UNTIL"); strings.HasSuffix(strings.ToUpper(until), "Z") {
+				rule.Until = rule.Until.Add(time.Duration(obs.offsetFrom) * time.Second)
+			}
+			event := Event{Start: obs.start, RRule: rule}
+			starts = append(starts, event.occurrences(tzifEnd)...)
+		}
+
+		for _, start := range starts {
+			at := start.Unix() - int64(obs.offsetFrom)
+			if at >= math.MinInt32 && at <= math.MaxInt32 {
+				transitions = append(transitions, transition{at: at, typ: i})
+			}
+		}
+	}
+	sort.SliceStable(transitions, func(i, j int) bool {
+		return transitions[i].at < transitions[j].at
+	})
+
+	var times bytes.Buffer
+	var indexes []byte
+	last := int64(math.MinInt64)
+	for _, t := range transitions {
+		if t.at == last {
+			continue
+		}
+		last = t.at
+		binary.Write(&times, binary.BigEndian, int32(t.at))
+		indexes = append(indexes, byte(t.typ))
+	}
+
+	var types bytes.Buffer
+	var names []byte
+	for _, obs := range tz.observances {
+		binary.Write(&types, binary.BigEndian, int32(obs.offsetTo))
+		isDST := byte(0)
+		if obs.daylight {
+			isDST = 1
+		}
+		types.WriteByte(isDST)
+		types.WriteByte(byte(len(names)))
+		names = append(names, obs.name...)
+		names = append(names, 0)
+	}
+	if len(tz.observances) > math.MaxUint8 || len(names) > math.MaxUint8 {
+		return nil, fmt.Errorf("%w %q", ErrUnknownTimezone, tz.id)
+	}
+
+	// TZif v1：头部、切换时间、每次切换的类型、类型定义、时区缩写
+	var data bytes.Buffer
+	data.WriteString("TZif")
+	data.Write(make([]byte, 16))
+	for _, n := range []int{0, 0, 0, len(indexes), len(tz.observances), len(names)} {
+		binary.Write(&data, binary.BigEndian, uint32(n))
+	}
+	data.Write(times.Bytes())
+	data.Write(indexes)
+	data.Write(types.Bytes())
+	data.Write(names)
+
+	return time.LoadLocationFromTZData(tz.id, data.Bytes())
+}
+
+func rruleValue(rrule, key string) string {
+	for _, part := range strings.Split(rrule, ";") {
+		if k, v, ok := strings.Cut(part, "="); ok && strings.EqualFold(k, key) {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
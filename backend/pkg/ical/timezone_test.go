@@ -0,0 +1,151 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// 没有对应IANA名称的自定义时区，规则与美国东部时间相同
+const customTimezone = `BEGIN:VTIMEZONE
+TZID:Custom Eastern
+BEGIN:STANDARD
+DTSTART:20071104T020000
+RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=1SU
+TZOFFSETFROM:-0400
+TZOFFSETTO:-0500
+TZNAME:EST
+END:STANDARD
+BEGIN:DAYLIGHT
+DTSTART:20070311T020000
+RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=2SU
+TZOFFSETFROM:-0500
+TZOFFSETTO:-0400
+TZNAME:EDT
+END:DAYLIGHT
+END:VTIMEZONE
+`
+
+func parseEvent(t *testing.T, tzid, start string, extra string) *Calendar {
+	t.Helper()
+	ics := "BEGIN:VCALENDAR\n" +
+		"BEGIN:VEVENT\nUID:1\nDTSTART;TZID=" + tzid + ":" + start + "\nDTEND;TZID=" + tzid + ":" + start + "\nEND:VEVENT\n" +
+		extra + "END:VCALENDAR\n"
+	calendar, err := Parse(strings.NewReader(ics), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return calendar
+}
+
+func TestParseTimezones(t *testing.T) {
+	tests := []struct {
+		name  string
+		tzid  string
+		start string
+		extra string
+		want  time.Time
+	}{
+		{"iana", "Asia/Shanghai", "20240115T090000", "", time.Date(2024, 1, 15, 1, 0, 0, 0, time.UTC)},
+		{"windows", "China Standard Time", "20240115T090000", "", time.Date(2024, 1, 15, 1, 0, 0, 0, time.UTC)},
+		{"windows with daylight saving", `"W. Europe Standard Time"`, "20240715T090000", "", time.Date(2024, 7, 15, 7, 0, 0, 0, time.UTC)},
+		{"mozilla prefix", "/mozilla.org/20050126_1/Europe/Berlin", "20240115T090000", "", time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC)},
+		{
+			name:  "x-lic-location",
+			tzid:  "Office",
+			start: "20240115T090000",
+			extra: "BEGIN:VTIMEZONE\nTZID:Office\nX-LIC-LOCATION:Asia/Tokyo\nBEGIN:STANDARD\nDTSTART:19700101T000000\nTZOFFSETFROM:+0900\nTZOFFSETTO:+0900\nEND:STANDARD\nEND:VTIMEZONE\n",
+			want:  time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{"vtimezone standard time", "Custom Eastern", "20240115T090000", customTimezone, time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)},
+		{"vtimezone daylight time", "Custom Eastern", "20240715T090000", customTimezone, time.Date(2024, 7, 15, 13, 0, 0, 0, time.UTC)},
+		{"vtimezone after switching", "Custom Eastern", "20241104T090000", customTimezone, time.Date(2024, 11, 4, 14, 0, 0, 0, time.UTC)},
+		{
+			name:  "vtimezone fixed offset",
+			tzid:  "India",
+			start: "20240115T090000",
+			extra: "BEGIN:VTIMEZONE\nTZID:India\nBEGIN:STANDARD\nDTSTART:16010101T000000\nTZOFFSETFROM:+0530\nTZOFFSETTO:+0530\nEND:STANDARD\nEND:VTIMEZONE\n",
+			want:  time.Date(2024, 1, 15, 3, 30, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calendar := parseEvent(t, tt.tzid, tt.start, tt.extra)
+			if len(calendar.UnknownTimezones) > 0 || len(calendar.Events) != 1 {
+				t.Fatalf("unexpected result %+v", calendar)
+			}
+			if got := calendar.Events[0].Start; !got.Equal(tt.want) {
+				t.Errorf("Start = %v, want %v", got.UTC(), tt.want)
+			}
+		})
+	}
+}
+
+// 重复事件在自定义时区中跨夏令时保持当地时间
+func TestExpandInVTimezone(t *testing.T) {
+	ics := "BEGIN:VCALENDAR\n" + customTimezone +
+		"BEGIN:VEVENT\nUID:weekly\nDTSTART;TZID=Custom Eastern:20240303T090000\nDURATION:PT30M\nRRULE:FREQ=WEEKLY;COUNT=2\nEND:VEVENT\n" +
+		"END:VCALENDAR\n"
+	calendar, err := Parse(strings.NewReader(ics), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := Expand(calendar.Events, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC))
+	want := []time.Time{
+		time.Date(2024, 3, 3, 14, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 10, 13, 0, 0, 0, time.UTC),
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+	for i, e := range events {
+		if !e.Start.Equal(want[i]) {
+			t.Errorf("event %d starts at %v, want %v", i, e.Start.UTC(), want[i])
+		}
+	}
+}
+
+// 无法识别的时区不再按UTC猜测，跳过事件并记录TZID
+func TestUnknownTimezone(t *testing.T) {
+	ics := "BEGIN:VCALENDAR\n" +
+		"BEGIN:VEVENT\nUID:1\nDTSTART;TZID=Mars/Olympus_Mons:20240115T090000\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nUID:2\nDTSTART;TZID=Mars/Olympus_Mons:20240115T090000Z\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nUID:3\nDTSTART:20240115T090000\nEXDATE;TZID=Mars/Olympus_Mons:20240116T090000\nRRULE:FREQ=DAILY\nEND:VEVENT\n" +
+		"END:VCALENDAR\n"
+	calendar, err := Parse(strings.NewReader(ics), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(calendar.UnknownTimezones) != 1 || calendar.UnknownTimezones[0] != "Mars/Olympus_Mons" {
+		t.Errorf("UnknownTimezones = %v", calendar.UnknownTimezones)
+	}
+	// UTC时间与TZID无关，照常解析
+	if len(calendar.Events) != 1 || calendar.Events[0].UID != "2" {
+		t.Errorf("unexpected events %+v", calendar.Events)
+	}
+}
+
+func TestParseOffset(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{"+0800", 8 * 3600, false},
+		{"-0430", -(4*3600 + 30*60), false},
+		{"+053045", 5*3600 + 30*60 + 45, false},
+		{"0800", 0, true},
+		{"+08", 0, true},
+		{"+0860", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseOffset(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseOffset(%q) = %d, %v", tt.value, got, err)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Package safehttp 提供只访问公网地址的HTTP客户端，用于请求用户填写的地址，
+// 如订阅的日历和自建的GitLab、Gitea，避免服务器被用来访问内网
+package safehttp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrUnsupportedScheme = errors.New("safehttp: only http and https are allowed")
+	ErrForbiddenAddress  = errors.New("safehttp: address is not allowed")
+)
+
+const maxRedirects = 10
+
+// 除net.IP自带判断外还需要拒绝的地址段
+var blockedNets = mustParseCIDRs(
+	"0.0.0.0/8",      // 本网络
+	"100.64.0.0/10",  // 运营商级NAT
+	"192.0.0.0/24",   // IETF协议分配
+	"198.18.0.0/15",  // 基准测试
+	"240.0.0.0/4",    // 保留
+	"64:ff9b::/96",   // NAT64，内嵌IPv4地址
+	"64:ff9b:1::/48", // 本地NAT64
+	"2001:db8::/32",  // 文档
+)
+
+// NewClient 返回的客户端在建立连接时检查解析后的IP，重定向的目标同样检查，
+// 因此DNS指向内网或重定向到内网的地址都会被拒绝
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   control,
+	}
+
+	transport := &http.Transport{
+		// 不使用环境变量中的代理，否则检查的是代理的地址
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	return &http.Client{
+		Timeout:       timeout,
+		Transport:     transport,
+		CheckRedirect: checkRedirect,
+	}
+}
+
+// ValidateURL 检查地址的协议和主机，保存用户填写的地址前调用。
+// 只做静态检查，IP在连接时由客户端检查
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ErrUnsupportedScheme
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("safehttp: missing host in %q", raw)
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !Allowed(ip) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// Allowed 判断IP是否为可以访问的公网地址
+func Allowed(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, n := range blockedNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !Allowed(ip) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("safehttp: stopped after %d redirects", maxRedirects)
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return ErrUnsupportedScheme
+	}
+	return nil
+}
+
+// IsForbidden 判断请求是否因为地址不允许而失败
+func IsForbidden(err error) bool {
+	return errors.Is(err, ErrForbiddenAddress) || errors.Is(err, ErrUnsupportedScheme)
+}
+
+// 只在初始化时调用
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
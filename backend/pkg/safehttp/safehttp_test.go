@@ -0,0 +1,90 @@
+package safehttp
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::ffff:10.0.0.1", false},
+		{"64:ff9b::a00:1", false},
+	}
+
+	for _, tt := range tests {
+		if got := Allowed(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("Allowed(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://calendar.example.com/basic.ics", false},
+		{"http://example.com", false},
+		{"file:///etc/passwd", true},
+		{"gopher://example.com", true},
+		{"https://", true},
+		{"http://127.0.0.1:8080/", true},
+		{"http://[::1]/", true},
+		{"http://169.254.169.254/latest/meta-data/", true},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateURL(tt.url); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateURL(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+		}
+	}
+}
+
+func TestClientRejectsLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal"))
+	}))
+	defer server.Close()
+
+	_, err := NewClient(5 * time.Second).Get(server.URL)
+	if !IsForbidden(err) {
+		t.Fatalf("expected forbidden address error, got %v", err)
+	}
+}
+
+// 主机名解析到内网地址时同样拒绝，检查发生在DNS解析之后
+func TestClientRejectsResolvedLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	_, err := NewClient(5 * time.Second).Get("http://localhost:" + port)
+	if !IsForbidden(err) {
+		t.Fatalf("expected forbidden address error, got %v", err)
+	}
+}
+
+func TestClientRejectsRedirectScheme(t *testing.T) {
+	req, _ := http.NewRequest("GET", "file:///etc/passwd", nil)
+	if err := checkRedirect(req, nil); !IsForbidden(err) {
+		t.Fatalf("expected unsupported scheme error, got %v", err)
+	}
+}
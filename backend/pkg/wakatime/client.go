@@ -0,0 +1,110 @@
+package wakatime
+
+import (
+	"encoding/base64"
+	"errors"
+	"myvault-backend/pkg/httpapi"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnauthorized = errors.New("wakatime: unauthorized")
+	ErrRateLimited  = errors.New("wakatime: rate limit exceeded")
+)
+
+// APIError 表示WakaTime返回的非2xx响应，可用errors.Is匹配上面的哨兵错误
+type APIError = httpapi.Error
+
+// Client 兼容WakaTime API的服务，包括自建的Wakapi
+type Client struct {
+	baseURL string
+	api     *httpapi.Client
+}
+
+type User struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Timezone string `json:"timezone"`
+}
+
+// Item 是某一维度（项目、语言、编辑器）的时长
+type Item struct {
+	Name         string  `json:"name"`
+	TotalSeconds float64 `json:"total_seconds"`
+	Percent      float64 `json:"percent"`
+}
+
+type Summary struct {
+	GrandTotal struct {
+		TotalSeconds float64 `json:"total_seconds"`
+		Text         string  `json:"text"`
+	} `json:"grand_total"`
+	Range struct {
+		Date     string `json:"date"`
+		Timezone string `json:"timezone"`
+	} `json:"range"`
+	Projects  []Item `json:"projects"`
+	Languages []Item `json:"languages"`
+	Editors   []Item `json:"editors"`
+}
+
+// baseURL为API根地址，如 https://wakatime.com/api/v1，
+// Wakapi为 https://wakapi.example.com/api/compat/wakatime/v1
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		api: &httpapi.Client{
+			Service:    "wakatime",
+			HTTPClient: &http.Client{Timeout: 30 * time.Second},
+			// 密钥没有权限时返回403，同样需要用户重新绑定
+			Classify: httpapi.Kinds{
+				Unauthorized: ErrUnauthorized,
+				Forbidden:    ErrUnauthorized,
+				RateLimited:  ErrRateLimited,
+			}.Classify,
+			MessageFields: []string{"error"},
+		},
+	}
+}
+
+// SetHTTPClient 替换发送请求的客户端，访问用户填写的API地址时使用safehttp
+func (c *Client) SetHTTPClient(httpClient *http.Client) {
+	c.api.HTTPClient = httpClient
+}
+
+func (c *Client) GetCurrentUser(apiKey string) (*User, error) {
+	var result struct {
+		Data User `json:"data"`
+	}
+	if err := c.get(apiKey, c.baseURL+"/users/current", &result); err != nil {
+		return nil, err
+	}
+
+	return &result.Data, nil
+}
+
+// GetSummaries 获取[start, end]之间每天的编码时长，日期按WakaTime账号设置的时区划分
+func (c *Client) GetSummaries(apiKey string, start, end time.Time) ([]Summary, error) {
+	params := url.Values{}
+	params.Set("start", start.Format("2006-01-02"))
+	params.Set("end", end.Format("2006-01-02"))
+
+	var result struct {
+		Data []Summary `json:"data"`
+	}
+	if err := c.get(apiKey, c.baseURL+"/users/current/summaries?"+params.Encode(), &result); err != nil {
+		return nil, err
+	}
+
+	return result.Data, nil
+}
+
+func (c *Client) get(apiKey, endpoint string, v interface{}) error {
+	// WakaTime和Wakapi都支持将API Key作为Basic认证的用户名
+	_, err := c.api.Get(endpoint, "Basic "+base64.StdEncoding.EncodeToString([]byte(apiKey)), v)
+	return err
+}
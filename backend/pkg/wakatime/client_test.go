@@ -0,0 +1,126 @@
+package wakatime
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewClient(server.URL + "/api/v1/")
+}
+
+func TestGetCurrentUser(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/users/current" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		// API Key作为Basic认证的用户名
+		if got, want := r.Header.Get("Authorization"), "Basic "+base64.StdEncoding.EncodeToString([]byte("waka_key")); got != want {
+			t.Errorf("Authorization = %q, want %q", got, want)
+		}
+		fmt.Fprint(w, `{"data": {"id": "u-1", "username": "carol", "email": "carol@example.com", "timezone": "Asia/Shanghai"}}`)
+	})
+
+	user, err := client.GetCurrentUser("waka_key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != "u-1" || user.Username != "carol" || user.Timezone != "Asia/Shanghai" {
+		t.Errorf("unexpected user %+v", user)
+	}
+}
+
+func TestGetSummaries(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/users/current/summaries" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if query := r.URL.Query(); query.Get("start") != "2024-05-01" || query.Get("end") != "2024-05-02" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		fmt.Fprint(w, `{
+			"data": [{
+				"grand_total": {"total_seconds": 5400.5, "text": "1 hr 30 mins"},
+				"range": {"date": "2024-05-01", "timezone": "Asia/Shanghai"},
+				"projects": [{"name": "myvault", "total_seconds": 3600, "percent": 66.66}, {"name": "blog", "total_seconds": 1800.5, "percent": 33.34}],
+				"languages": [{"name": "Go", "total_seconds": 5000, "percent": 92.6}],
+				"editors": [{"name": "VS Code", "total_seconds": 5400.5, "percent": 100}]
+			}, {
+				"grand_total": {"total_seconds": 0, "text": "0 secs"},
+				"range": {"date": "2024-05-02", "timezone": "Asia/Shanghai"},
+				"projects": [],
+				"languages": [],
+				"editors": []
+			}],
+			"cumulative_total": {"seconds": 5400.5}
+		}`)
+	})
+
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)
+	summaries, err := client.GetSummaries("waka_key", start, start.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summaries) != 2 {
+		t.Fatalf("got %d summaries, want 2", len(summaries))
+	}
+
+	first := summaries[0]
+	if first.Range.Date != "2024-05-01" || first.GrandTotal.TotalSeconds != 5400.5 {
+		t.Errorf("unexpected summary %+v", first)
+	}
+	if len(first.Projects) != 2 || first.Projects[1].Name != "blog" || first.Projects[1].TotalSeconds != 1800.5 {
+		t.Errorf("unexpected projects %+v", first.Projects)
+	}
+	if len(first.Languages) != 1 || first.Languages[0].Name != "Go" || len(first.Editors) != 1 {
+		t.Errorf("unexpected languages or editors %+v %+v", first.Languages, first.Editors)
+	}
+	if second := summaries[1]; second.Range.Date != "2024-05-02" || second.GrandTotal.TotalSeconds != 0 || len(second.Projects) != 0 {
+		t.Errorf("unexpected summary %+v", second)
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+	tests := []struct {
+		status  int
+		body    string
+		want    error
+		message string
+	}{
+		{http.StatusUnauthorized, `{"error": "Unauthorized: invalid api key"}`, ErrUnauthorized, "Unauthorized: invalid api key"},
+		// 密钥没有权限时同样需要重新绑定
+		{http.StatusForbidden, `{"error": "Forbidden"}`, ErrUnauthorized, "Forbidden"},
+		{http.StatusTooManyRequests, `{"error": "Rate limited"}`, ErrRateLimited, "Rate limited"},
+		{http.StatusNotFound, `{"error": "Not found"}`, nil, "Not found"},
+	}
+
+	for _, tt := range tests {
+		client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			fmt.Fprint(w, tt.body)
+		})
+
+		_, err := client.GetSummaries("waka_key", time.Now(), time.Now())
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("status %d: expected APIError, got %v", tt.status, err)
+		}
+		if apiErr.StatusCode != tt.status || apiErr.Message != tt.message {
+			t.Errorf("status %d: unexpected error %+v", tt.status, apiErr)
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("status %d: expected %v, got %v", tt.status, tt.want, err)
+		}
+		if tt.want == nil && (errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrRateLimited)) {
+			t.Errorf("status %d: unexpected classification %v", tt.status, err)
+		}
+	}
+}
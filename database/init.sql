@@ -18,6 +18,22 @@ CREATE TABLE IF NOT EXISTS users (
     github_username VARCHAR(100),
     github_id VARCHAR(50),
     access_token TEXT,
+    gitlab_username VARCHAR(100),
+    gitlab_email VARCHAR(100),
+    gitlab_base_url VARCHAR(255),
+    gitlab_token TEXT,
+    gitea_username VARCHAR(100),
+    gitea_email VARCHAR(100),
+    gitea_base_url VARCHAR(255),
+    gitea_token TEXT,
+    wakatime_username VARCHAR(100),
+    wakatime_base_url VARCHAR(255),
+    wakatime_api_key TEXT,
+    local_repo_paths TEXT,
+    local_git_email VARCHAR(100),
+    include_forks BOOLEAN DEFAULT TRUE,
+    include_archived BOOLEAN DEFAULT FALSE,
+    all_branches BOOLEAN DEFAULT FALSE,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     deleted_at TIMESTAMP NULL
@@ -30,14 +46,23 @@ CREATE TABLE IF NOT EXISTS activities (
     date DATE NOT NULL,
     summary TEXT,
     ai_generated BOOLEAN DEFAULT FALSE,
+    summary_status VARCHAR(20),
+    summary_error TEXT,
+    summary_attempts INT DEFAULT 0,
+    summary_fingerprint VARCHAR(64),
     has_activity BOOLEAN DEFAULT FALSE,
     commit_count INT DEFAULT 0,
+    pull_request_count INT DEFAULT 0,
+    review_count INT DEFAULT 0,
+    issue_count INT DEFAULT 0,
     total_time INT DEFAULT 0,
+    time_source VARCHAR(20),
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     deleted_at TIMESTAMP NULL,
     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
-    INDEX idx_user_date (user_id, date)
+    UNIQUE INDEX idx_activities_user_date (user_id, date),
+    INDEX idx_activities_summary_status (summary_status)
 );
 
 -- 数据源表
@@ -55,6 +80,7 @@ CREATE TABLE IF NOT EXISTS commits (
     id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
     activity_id BIGINT UNSIGNED NOT NULL,
     hash VARCHAR(40) NOT NULL,
+    source VARCHAR(50) DEFAULT 'github',
     message TEXT NOT NULL,
     repository VARCHAR(255),
     author VARCHAR(100),
@@ -62,20 +88,175 @@ CREATE TABLE IF NOT EXISTS commits (
     files INT DEFAULT 0,
     additions INT DEFAULT 0,
     deletions INT DEFAULT 0,
+    partial BOOLEAN DEFAULT FALSE,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (activity_id) REFERENCES activities(id) ON DELETE CASCADE,
     INDEX idx_activity_time (activity_id, time)
 );
 
+-- Pull Request记录表
+CREATE TABLE IF NOT EXISTS pull_requests (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    activity_id BIGINT UNSIGNED NOT NULL,
+    external_id VARCHAR(255) NOT NULL,
+    repository VARCHAR(255),
+    number INT,
+    title TEXT,
+    action VARCHAR(50),
+    state VARCHAR(50),
+    url VARCHAR(255),
+    time TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (activity_id) REFERENCES activities(id) ON DELETE CASCADE,
+    INDEX idx_activity (activity_id)
+);
+
+-- 代码评审记录表
+CREATE TABLE IF NOT EXISTS reviews (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    activity_id BIGINT UNSIGNED NOT NULL,
+    external_id VARCHAR(255) NOT NULL,
+    repository VARCHAR(255),
+    pull_number INT,
+    pull_title TEXT,
+    state VARCHAR(50),
+    url VARCHAR(255),
+    time TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (activity_id) REFERENCES activities(id) ON DELETE CASCADE,
+    INDEX idx_activity (activity_id)
+);
+
+-- Issue记录表
+CREATE TABLE IF NOT EXISTS issue_events (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    activity_id BIGINT UNSIGNED NOT NULL,
+    external_id VARCHAR(255) NOT NULL,
+    repository VARCHAR(255),
+    number INT,
+    title TEXT,
+    action VARCHAR(50),
+    state VARCHAR(50),
+    url VARCHAR(255),
+    time TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (activity_id) REFERENCES activities(id) ON DELETE CASCADE,
+    INDEX idx_activity (activity_id)
+);
+
+-- 手动记录表
+CREATE TABLE IF NOT EXISTS manual_entries (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    activity_id BIGINT UNSIGNED NOT NULL,
+    user_id BIGINT UNSIGNED NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    body TEXT,
+    duration INT DEFAULT 0,
+    tags TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (activity_id) REFERENCES activities(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_activity (activity_id),
+    INDEX idx_user (user_id)
+);
+
+-- 日历表
+CREATE TABLE IF NOT EXISTS calendars (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    url VARCHAR(1024),
+    content LONGTEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_user (user_id)
+);
+
+-- webhook表
+CREATE TABLE IF NOT EXISTS webhooks (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    token VARCHAR(64) NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    last_used_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE INDEX idx_webhooks_token (token),
+    INDEX idx_user (user_id)
+);
+
+-- 周、月、年汇总表
+CREATE TABLE IF NOT EXISTS period_summaries (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    period_type VARCHAR(10) NOT NULL,
+    period VARCHAR(10) NOT NULL,
+    start_date DATE,
+    end_date DATE,
+    summary TEXT,
+    ai_generated BOOLEAN DEFAULT FALSE,
+    status VARCHAR(20),
+    error TEXT,
+    active_days INT DEFAULT 0,
+    commit_count INT DEFAULT 0,
+    pull_request_count INT DEFAULT 0,
+    review_count INT DEFAULT 0,
+    issue_count INT DEFAULT 0,
+    total_time INT DEFAULT 0,
+    repositories TEXT,
+    fingerprint VARCHAR(64),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE INDEX idx_user_period (user_id, period_type, period),
+    INDEX idx_period_summaries_status (status)
+);
+
 -- 仓库表
 CREATE TABLE IF NOT EXISTS repositories (
     id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    source VARCHAR(50) NOT NULL DEFAULT 'github',
     name VARCHAR(255) NOT NULL,
     full_name VARCHAR(255) NOT NULL,
     description TEXT,
     language VARCHAR(50),
     private BOOLEAN DEFAULT FALSE,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    fork BOOLEAN DEFAULT FALSE,
+    archived BOOLEAN DEFAULT FALSE,
+    status VARCHAR(20) NOT NULL DEFAULT 'tracked',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE INDEX idx_user_source_repo (user_id, source, full_name)
+);
+
+-- 同步游标表
+CREATE TABLE IF NOT EXISTS sync_cursors (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    source VARCHAR(50) NOT NULL,
+    last_sync_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE INDEX idx_user_source (user_id, source)
+);
+
+-- 仓库同步游标表
+CREATE TABLE IF NOT EXISTS repository_cursors (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    sync_cursor_id BIGINT UNSIGNED NOT NULL,
+    repository VARCHAR(255) NOT NULL,
+    last_sha VARCHAR(40),
+    last_commit_at TIMESTAMP NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (sync_cursor_id) REFERENCES sync_cursors(id) ON DELETE CASCADE,
+    UNIQUE INDEX idx_cursor_repo (sync_cursor_id, repository)
 );
 
 -- 插入示例数据
@@ -86,10 +267,10 @@ INSERT INTO users (username, email, password, avatar) VALUES
 SET @user_id = LAST_INSERT_ID();
 
 -- 插入示例活动
-INSERT INTO activities (user_id, date, summary, ai_generated, has_activity, commit_count) VALUES
-(@user_id, CURDATE(), '今天完成了React组件优化和API接口开发', TRUE, TRUE, 5),
-(@user_id, DATE_SUB(CURDATE(), INTERVAL 1 DAY), '实现了用户认证系统和GitHub集成', TRUE, TRUE, 8),
-(@user_id, DATE_SUB(CURDATE(), INTERVAL 2 DAY), '今日无编程活动', FALSE, FALSE, 0);
+INSERT INTO activities (user_id, date, summary, ai_generated, summary_status, has_activity, commit_count) VALUES
+(@user_id, CURDATE(), '今天完成了React组件优化和API接口开发', TRUE, 'done', TRUE, 5),
+(@user_id, DATE_SUB(CURDATE(), INTERVAL 1 DAY), '实现了用户认证系统和GitHub集成', TRUE, 'done', TRUE, 8),
+(@user_id, DATE_SUB(CURDATE(), INTERVAL 2 DAY), '今日无编程活动', FALSE, 'skipped', FALSE, 0);
 
 -- 获取活动ID
 SET @activity_id = (SELECT id FROM activities WHERE user_id = @user_id AND date = CURDATE());